@@ -2012,7 +2012,10 @@ func mint(chaindata string, block uint64) error {
 				return fmt.Errorf("invalid block body RLP: %w", err)
 			}
 			header := rawdb.ReadHeader(db, blockHash, blockNumber)
-			senders := rawdb.ReadSenders(db, blockHash, blockNumber)
+			senders, err := rawdb.ReadSendersOrRecover(db, nil, blockHash, blockNumber)
+			if err != nil {
+				return err
+			}
 			var ethSpent uint256.Int
 			var ethSpentTotal uint256.Int
 			var totalGas uint256.Int
@@ -1,6 +1,8 @@
 package commands
 
 import (
+	"runtime"
+
 	"github.com/ledgerwatch/turbo-geth/node"
 	"github.com/spf13/cobra"
 )
@@ -10,12 +12,19 @@ var (
 	compact            bool
 	referenceChaindata string
 	block              uint64
+	fromBlock          uint64
 	unwind             uint64
 	unwindEvery        uint64
 	hdd                bool
 	reset              bool
 	bucket             string
 	datadir            string
+	workers            int
+
+	maxHeapAlloc       string
+	lockOSThread       bool
+	maxTempFilesSize   string
+	skipInvalidChainID bool
 )
 
 func must(err error) {
@@ -43,6 +52,10 @@ func withBlock(cmd *cobra.Command) {
 	cmd.Flags().Uint64Var(&block, "block", 0, "block test at this block")
 }
 
+func withFromBlock(cmd *cobra.Command) {
+	cmd.Flags().Uint64Var(&fromBlock, "from", 0, "start from this block instead of the stage progress")
+}
+
 func withUnwind(cmd *cobra.Command) {
 	cmd.Flags().Uint64Var(&unwind, "unwind", 0, "how much blocks unwind on each iteration")
 }
@@ -66,3 +79,14 @@ func withDatadir(cmd *cobra.Command) {
 func withHDD(cmd *cobra.Command) {
 	cmd.Flags().BoolVar(&hdd, "hdd", false, "optimizations valuable for HDD")
 }
+
+func withSendersConfig(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&maxHeapAlloc, "max_heap", "", "pause reading block bodies while the allocated heap is above this size, e.g. 8GB")
+	cmd.Flags().BoolVar(&lockOSThread, "lock_os_thread", false, "lock every goroutine recovering senders to its own OS thread")
+	cmd.Flags().StringVar(&maxTempFilesSize, "max_temp_files", "", "load recovered senders into the db once temporary files reach this size, e.g. 20GB")
+	cmd.Flags().BoolVar(&skipInvalidChainID, "skip_invalid_chainid", false, "store the zero address as sender of transactions signed for another chain instead of failing, execution refuses such blocks")
+}
+
+func withWorkers(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&workers, "workers", runtime.NumCPU(), "amount of goroutines used to recover senders")
+}
@@ -2,9 +2,10 @@ package commands
 
 import (
 	"context"
-	"runtime"
+	"fmt"
 	"time"
 
+	"github.com/c2h5oh/datasize"
 	"github.com/ledgerwatch/turbo-geth/cmd/utils"
 	"github.com/ledgerwatch/turbo-geth/consensus/ethash"
 	"github.com/ledgerwatch/turbo-geth/core"
@@ -30,6 +31,19 @@ var cmdStageSenders = &cobra.Command{
 	},
 }
 
+var cmdVerifySenders = &cobra.Command{
+	Use:   "verify_senders",
+	Short: "Compare stored senders with recovered ones, doesn't change the stage progress",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := utils.RootContext()
+		if err := verifySenders(ctx); err != nil {
+			log.Error("Error", "err", err)
+			return err
+		}
+		return nil
+	},
+}
+
 var cmdStageExec = &cobra.Command{
 	Use:   "stage_exec",
 	Short: "",
@@ -126,11 +140,23 @@ func init() {
 	withChaindata(cmdStageSenders)
 	withReset(cmdStageSenders)
 	withBlock(cmdStageSenders)
+	withFromBlock(cmdStageSenders)
 	withUnwind(cmdStageSenders)
 	withDatadir(cmdStageSenders)
+	withWorkers(cmdStageSenders)
+	withSendersConfig(cmdStageSenders)
 
 	rootCmd.AddCommand(cmdStageSenders)
 
+	withChaindata(cmdVerifySenders)
+	withBlock(cmdVerifySenders)
+	withFromBlock(cmdVerifySenders)
+	withDatadir(cmdVerifySenders)
+	withWorkers(cmdVerifySenders)
+	withSendersConfig(cmdVerifySenders)
+
+	rootCmd.AddCommand(cmdVerifySenders)
+
 	withChaindata(cmdStageExec)
 	withReset(cmdStageExec)
 	withBlock(cmdStageExec)
@@ -197,25 +223,80 @@ func stageSenders(ctx context.Context) error {
 	stage3 := progress(stages.Senders)
 	log.Info("Stage2", "progress", stage2.BlockNumber)
 	log.Info("Stage3", "progress", stage3.BlockNumber)
-	ch := make(chan struct{})
-	defer close(ch)
-
-	const batchSize = 10000
-	const blockSize = 4096
-	n := runtime.NumCPU()
-
-	cfg := stagedsync.Stage3Config{
-		BatchSize:       batchSize,
-		BlockSize:       blockSize,
-		BufferSize:      (blockSize * 10 / 20) * 10000, // 20*4096
-		StartTrace:      false,
-		Prof:            false,
-		NumOfGoroutines: n,
-		ReadChLen:       4,
-		Now:             time.Now(),
+
+	to := stage2.BlockNumber
+	if block > 0 && block < to {
+		to = block
+	}
+	if fromBlock > 0 {
+		// re-run recovery over the given range regardless of the stage progress. The stage saves the end of the range
+		// as its progress, so the range must not end below the blocks later stages already consumed.
+		// --reset resets the progress before it's read, so the range is allowed then.
+		if to < stage3.BlockNumber {
+			return fmt.Errorf("range %d-%d ends below Senders progress %d, use --reset to re-run it anyway", fromBlock, to, stage3.BlockNumber)
+		}
+		// blocks between the progress and the range would be skipped, but the progress would pass them
+		if fromBlock > stage3.BlockNumber+1 {
+			return fmt.Errorf("range %d-%d starts after Senders progress %d, start it at %d at the latest", fromBlock, to, stage3.BlockNumber, stage3.BlockNumber+1)
+		}
+		stage3.BlockNumber = fromBlock - 1
+	}
+
+	cfg, err := sendersConfig()
+	if err != nil {
+		return err
 	}
 
-	return stagedsync.SpawnRecoverSendersStage(cfg, stage3, db, params.MainnetChainConfig, block, datadir, ch)
+	start := time.Now()
+	if err := stagedsync.SpawnRecoverSendersStage(ctx, cfg, stage3, db, params.MainnetChainConfig, block, datadir); err != nil {
+		return err
+	}
+	if to > stage3.BlockNumber {
+		took := time.Since(start)
+		log.Info("Senders recovered", "from", stage3.BlockNumber+1, "to", to, "took", took,
+			"blk/second", float64(to-stage3.BlockNumber)/took.Seconds())
+	}
+	return nil
+}
+
+// sendersConfig - senders stage configuration from the command line flags
+func sendersConfig() (stagedsync.Stage3Config, error) {
+	cfg := stagedsync.DefaultStage3Config()
+	cfg.NumOfGoroutines = workers
+	cfg.LockOSThread = lockOSThread
+	cfg.SkipInvalidChainID = skipInvalidChainID
+	var size datasize.ByteSize
+	if maxHeapAlloc != "" {
+		if err := size.UnmarshalText([]byte(maxHeapAlloc)); err != nil {
+			return cfg, fmt.Errorf("invalid --max_heap: %w", err)
+		}
+		cfg.MaxHeapAlloc = size.Bytes()
+	}
+	if maxTempFilesSize != "" {
+		if err := size.UnmarshalText([]byte(maxTempFilesSize)); err != nil {
+			return cfg, fmt.Errorf("invalid --max_temp_files: %w", err)
+		}
+		cfg.MaxTempFilesSize = int(size.Bytes())
+	}
+	return cfg, nil
+}
+
+// verifySenders checks blocks from --from (1 by default) up to the Senders progress or --block,
+// it only reads the db: neither senders nor the stage progress are written
+func verifySenders(ctx context.Context) error {
+	db := ethdb.MustOpen(chaindata)
+	defer db.Close()
+
+	start := uint64(0)
+	if fromBlock > 0 {
+		start = fromBlock - 1
+	}
+	cfg, err := sendersConfig()
+	if err != nil {
+		return err
+	}
+	cfg.Verify = true
+	return stagedsync.SpawnRecoverSendersStage(ctx, cfg, &stagedsync.StageState{Stage: stages.Senders, BlockNumber: start}, db, params.MainnetChainConfig, block, datadir)
 }
 
 func stageExec(ctx context.Context) error {
@@ -142,6 +142,7 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest) (Pa
 				historyFilter = req.ToAddress
 			}
 
+			chainConfig := getChainConfig(api.dbReader)
 			for _, addr := range historyFilter {
 
 				addrBytes := addr.Bytes()
@@ -153,7 +154,10 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest) (Pa
 				for _, num := range blockNumbers {
 
 					block := rawdb.ReadBlockByNumber(api.dbReader, num)
-					senders := rawdb.ReadSenders(api.dbReader, block.Hash(), num)
+					senders, err := rawdb.ReadSendersOrRecover(api.dbReader, chainConfig, block.Hash(), num)
+					if err != nil {
+						return err
+					}
 					txs := block.Transactions()
 					for i, tx := range txs {
 						if uint64(len(filteredHashes)) == maxTracesCount {
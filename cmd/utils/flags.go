@@ -48,6 +48,7 @@ import (
 	"github.com/ledgerwatch/turbo-geth/eth/downloader"
 	"github.com/ledgerwatch/turbo-geth/eth/gasprice"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/remote/remotedbserver"
 	"github.com/ledgerwatch/turbo-geth/internal/flags"
 	"github.com/ledgerwatch/turbo-geth/log"
 	"github.com/ledgerwatch/turbo-geth/miner"
@@ -410,11 +411,71 @@ var (
 		Name:  "hdd",
 		Usage: "Perform warm up loop during transaction replay stage to reduce the impact of high latency of HDD",
 	}
+	SendersWorkersFlag = cli.IntFlag{
+		Name:  "senders.workers",
+		Usage: "Number of goroutines recovering senders, 0 means one per crypto context (one per CPU)",
+	}
+	SendersMaxHeapFlag = cli.StringFlag{
+		Name:  "senders.maxHeap",
+		Usage: "Pause reading block bodies for senders recovery while the allocated heap is above this size, e.g. 8GB. Empty means no limit",
+	}
+	SendersLockOSThreadFlag = cli.BoolFlag{
+		Name:  "senders.lockOSThread",
+		Usage: "Lock every senders recovery goroutine to its own OS thread, pays off on machines with many cores",
+	}
+	SendersMaxTempFilesFlag = cli.StringFlag{
+		Name:  "senders.maxTempFiles",
+		Usage: "Load recovered senders into the db once their temporary files reach this size, e.g. 20GB. Empty means no limit. Not applied to sync cycles run in one transaction (shorter than 1024 blocks)",
+	}
+	SendersSkipInvalidChainIDFlag = cli.BoolFlag{
+		Name:  "senders.skipInvalidChainID",
+		Usage: "Store the zero address as sender of transactions signed for another chain instead of failing senders recovery. Execution still stops at such blocks. Not for consensus critical nodes",
+	}
 	PrivateApiAddr = cli.StringFlag{
 		Name:  "private.api.addr",
-		Usage: "private api network address, for example: 127.0.0.1:9090, empty string means not to start the listener. do not expose to public network. serves remote database interface",
+		Usage: "private api network address, for example: 127.0.0.1:9090 or unix:///path/to/socket, empty string means not to start the listener. do not expose to public network. serves remote database interface",
 		Value: "",
 	}
+	PrivateApiToken = cli.StringFlag{
+		Name:  "private.api.token",
+		Usage: "if set, clients of private api must send this token (bearer authentication)",
+		Value: "",
+	}
+	PrivateApiMaxStreams = cli.UintFlag{
+		Name:  "private.api.maxstreams",
+		Usage: "max concurrent streams per client connection of private api, increase it if many RPC clients are served",
+		Value: 40,
+	}
+	PrivateApiRateLimit = cli.Float64Flag{
+		Name:  "private.api.ratelimit",
+		Usage: "requests per second which one client host can send to private api, 0 means unlimited",
+		Value: 0,
+	}
+	PrivateApiRateBurst = cli.IntFlag{
+		Name:  "private.api.rateburst",
+		Usage: "how many requests above private.api.ratelimit one client host can send at once",
+		Value: 100,
+	}
+	PrivateApiBuckets = cli.StringFlag{
+		Name:  "private.api.buckets",
+		Usage: "comma separated list of buckets which clients of private api can access, for example: h,b. empty means all buckets",
+		Value: "",
+	}
+	PrivateApiMaxReadTxs = cli.IntFlag{
+		Name:  "private.api.maxreadtxs",
+		Usage: "max read transactions open by private api at once, requests above it wait private.api.maxreadtxs.wait, then fail. 0 means unlimited, 64 leaves room for the node's own readers",
+		Value: remotedbserver.DefaultMaxReadTxs,
+	}
+	PrivateApiMaxReadTxsWait = cli.DurationFlag{
+		Name:  "private.api.maxreadtxs.wait",
+		Usage: "how long a request to private api waits for a free read transaction",
+		Value: remotedbserver.DefaultMaxReadTxsWait,
+	}
+	PrivateApiStaleClientTimeout = cli.DurationFlag{
+		Name:  "private.api.staleclient.timeout",
+		Usage: "how long private api waits for a client to read streamed data before releasing its read transaction, idle clients are not affected. negative means forever",
+		Value: remotedbserver.DefaultStaleClientTimeout,
+	}
 	// Miner settings
 	MiningEnabledFlag = cli.BoolFlag{
 		Name:  "mine",
@@ -994,6 +1055,16 @@ func SplitAndTrim(input string) (ret []string) {
 // read-only interface to the databae
 func setPrivateApi(ctx *cli.Context, cfg *node.Config) {
 	cfg.PrivateApiAddr = ctx.GlobalString(PrivateApiAddr.Name)
+	cfg.PrivateApiToken = ctx.GlobalString(PrivateApiToken.Name)
+	cfg.PrivateApiMaxStreams = uint32(ctx.GlobalUint(PrivateApiMaxStreams.Name))
+	cfg.PrivateApiRateLimit = ctx.GlobalFloat64(PrivateApiRateLimit.Name)
+	cfg.PrivateApiRateBurst = ctx.GlobalInt(PrivateApiRateBurst.Name)
+	cfg.PrivateApiMaxReadTxs = ctx.GlobalInt(PrivateApiMaxReadTxs.Name)
+	cfg.PrivateApiMaxReadTxsWait = ctx.GlobalDuration(PrivateApiMaxReadTxsWait.Name)
+	cfg.PrivateApiStaleClientTimeout = ctx.GlobalDuration(PrivateApiStaleClientTimeout.Name)
+	if buckets := ctx.GlobalString(PrivateApiBuckets.Name); buckets != "" {
+		cfg.PrivateApiBuckets = SplitAndTrim(buckets)
+	}
 	if ctx.GlobalBool(TLSFlag.Name) {
 		certFile := ctx.GlobalString(TLSCertFlag.Name)
 		keyFile := ctx.GlobalString(TLSKeyFlag.Name)
@@ -1556,6 +1627,11 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 
 	cfg.StorageMode = mode
 	cfg.Hdd = ctx.GlobalBool(HddFlag.Name)
+	cfg.SendersWorkers = ctx.GlobalInt(SendersWorkersFlag.Name)
+	cfg.SendersMaxHeapAlloc = byteSizeFlag(ctx, SendersMaxHeapFlag.Name)
+	cfg.SendersLockOSThread = ctx.GlobalBool(SendersLockOSThreadFlag.Name)
+	cfg.SendersMaxTempFilesSize = int(byteSizeFlag(ctx, SendersMaxTempFilesFlag.Name))
+	cfg.SendersSkipInvalidChainID = ctx.GlobalBool(SendersSkipInvalidChainIDFlag.Name)
 	cfg.ArchiveSyncInterval = ctx.GlobalInt(ArchiveSyncInterval.Name)
 
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
@@ -1688,6 +1764,19 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	//}
 }
 
+// byteSizeFlag parses a size flag like "8GB", empty value means 0
+func byteSizeFlag(ctx *cli.Context, name string) uint64 {
+	v := ctx.GlobalString(name)
+	if v == "" {
+		return 0
+	}
+	var size datasize.ByteSize
+	if err := size.UnmarshalText([]byte(v)); err != nil {
+		Fatalf("Invalid --%s: %v", name, err)
+	}
+	return size.Bytes()
+}
+
 // setDNSDiscoveryDefaults configures DNS discovery with the given URL if
 // no URLs are set.
 func setDNSDiscoveryDefaults(cfg *eth.Config, genesis common.Hash) {
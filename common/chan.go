@@ -1,6 +1,9 @@
 package common
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 
 var ErrStopped = errors.New("stopped")
 
@@ -16,6 +19,39 @@ func Stopped(ch <-chan struct{}) error {
 	return nil
 }
 
+// stoppedError is returned by ContextStopped, it matches both ErrStopped and the error of the context
+type stoppedError struct {
+	ctxErr error
+}
+
+func (e stoppedError) Error() string        { return ErrStopped.Error() + ": " + e.ctxErr.Error() }
+func (e stoppedError) Is(target error) bool { return target == ErrStopped }
+func (e stoppedError) Unwrap() error        { return e.ctxErr }
+
+// preallocated, so checking a cancelled context in hot loops doesn't allocate
+var (
+	errStoppedCanceled         error = stoppedError{context.Canceled}
+	errStoppedDeadlineExceeded error = stoppedError{context.DeadlineExceeded}
+)
+
+// ContextStopped is Stopped for code migrated from quit channels to context.Context.
+// The returned error matches both ErrStopped and ctx.Err() with errors.Is, so callers can check either.
+func ContextStopped(ctx context.Context) error {
+	if ctx == nil {
+		return nil
+	}
+	switch err := ctx.Err(); err {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		return errStoppedDeadlineExceeded
+	case context.Canceled:
+		return errStoppedCanceled
+	default:
+		return stoppedError{err}
+	}
+}
+
 func SafeClose(ch chan struct{}) {
 	if ch == nil {
 		return
@@ -27,3 +63,20 @@ func SafeClose(ch chan struct{}) {
 		close(ch)
 	}
 }
+
+// ContextFromQuitCh returns a context which is cancelled when the quit channel is closed.
+// The returned cancel function must be called to release the resources associated with the context.
+func ContextFromQuitCh(quit <-chan struct{}) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if quit == nil {
+		return ctx, cancel
+	}
+	go func() {
+		select {
+		case <-quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
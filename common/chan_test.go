@@ -0,0 +1,55 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestContextStopped(t *testing.T) {
+	if err := ContextStopped(context.Background()); err != nil {
+		t.Fatalf("not cancelled context: %v", err)
+	}
+	if err := ContextStopped(nil); err != nil { //nolint:staticcheck
+		t.Fatalf("nil context: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := ContextStopped(ctx)
+	if !errors.Is(err, ErrStopped) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled context: %v", err)
+	}
+
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+	err = ContextStopped(ctx)
+	if !errors.Is(err, ErrStopped) || !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		t.Fatalf("expired context: %v", err)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { _ = ContextStopped(ctx) }); allocs != 0 {
+		t.Fatalf("stopped check allocates %v times", allocs)
+	}
+}
+
+func BenchmarkContextStopped(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	b.Run("running", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := ContextStopped(ctx); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	cancel()
+	b.Run("cancelled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := ContextStopped(ctx); err == nil {
+				b.Fatal("expected error")
+			}
+		}
+	})
+}
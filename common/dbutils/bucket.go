@@ -167,6 +167,8 @@ var (
 	// last block that was pruned
 	// it's saved one in 5 minutes
 	LastPrunedBlockKey = []byte("LastPrunedBlock")
+	// lowest block whose senders are stored, senders of older blocks were pruned
+	SendersAvailableFromKey = []byte("SendersAvailableFrom")
 	//StorageModeHistory - does node save history.
 	StorageModeHistory = []byte("smHistory")
 	//StorageModeReceipts - does node save receipts.
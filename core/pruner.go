@@ -10,6 +10,7 @@ import (
 
 	"github.com/ledgerwatch/turbo-geth/common"
 	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
 	"github.com/ledgerwatch/turbo-geth/core/types"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
 	"github.com/ledgerwatch/turbo-geth/log"
@@ -91,6 +92,12 @@ func (p *BasicPruner) pruningLoop(db ethdb.Database) {
 				log.Error("PruneStorageOfSelfDestructedAccounts error", "err", err)
 				return
 			}
+			// senders of the pruned blocks are recovered from their bodies when they are read
+			err = rawdb.PruneSenders(db, to)
+			if err != nil {
+				log.Error("Pruning senders error", "err", err)
+				return
+			}
 			p.LastPrunedBlockNum = to
 		}
 	}
@@ -21,6 +21,7 @@ import (
 	"context"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/ledgerwatch/turbo-geth/ethdb"
@@ -31,6 +32,7 @@ import (
 	"github.com/ledgerwatch/turbo-geth/common/debug"
 	"github.com/ledgerwatch/turbo-geth/core/types"
 	"github.com/ledgerwatch/turbo-geth/log"
+	"github.com/ledgerwatch/turbo-geth/params"
 	"github.com/ledgerwatch/turbo-geth/rlp"
 
 	"github.com/golang/snappy"
@@ -324,6 +326,90 @@ func ReadSenders(db DatabaseReader, hash common.Hash, number uint64) []common.Ad
 	return senders
 }
 
+// SendersAvailableFrom returns the lowest block whose senders weren't pruned
+func SendersAvailableFrom(db DatabaseReader) (uint64, error) {
+	v, err := db.Get(dbutils.DatabaseInfoBucket, dbutils.SendersAvailableFromKey)
+	if err != nil && !errors.Is(err, ethdb.ErrKeyNotFound) {
+		return 0, err
+	}
+	if len(v) == 0 {
+		return 0, nil
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+// ReadSendersOrRecover reads stored senders of the block, or recovers them from its body if they were pruned.
+// Nil config means the chain config stored with the genesis block.
+func ReadSendersOrRecover(db DatabaseReader, config *params.ChainConfig, hash common.Hash, number uint64) ([]common.Address, error) {
+	availableFrom, err := SendersAvailableFrom(db)
+	if err != nil {
+		return nil, err
+	}
+	if number >= availableFrom {
+		return ReadSenders(db, hash, number), nil
+	}
+	body := ReadBody(db, hash, number)
+	if body == nil {
+		return nil, fmt.Errorf("senders of block %d are pruned and its body is missing", number)
+	}
+	if config == nil {
+		if config = ReadChainConfig(db, ReadCanonicalHash(db, 0)); config == nil {
+			return nil, fmt.Errorf("senders of block %d are pruned and chain config is missing", number)
+		}
+	}
+	signer := types.MakeSigner(config, new(big.Int).SetUint64(number))
+	senders := make([]common.Address, len(body.Transactions))
+	for i, tx := range body.Transactions {
+		if senders[i], err = types.Sender(signer, tx); err != nil {
+			return nil, fmt.Errorf("recovering sender of tx %d in block %d: %w", i, number, err)
+		}
+		if tx.Protected() && tx.ChainID().Cmp(signer.ChainID()) != 0 {
+			return nil, fmt.Errorf("recovering sender of tx %d in block %d: %w", i, number, types.ErrInvalidChainId)
+		}
+	}
+	return senders, nil
+}
+
+// PruneSenders deletes stored senders of the blocks below the given one, to reclaim space.
+// The new lowest block with stored senders is saved before anything is deleted, so senders of a partially pruned range
+// are recovered on demand by ReadSendersOrRecover, and calling PruneSenders again finishes the interrupted pruning.
+func PruneSenders(db ethdb.Database, below uint64) error {
+	availableFrom, err := SendersAvailableFrom(db)
+	if err != nil {
+		return err
+	}
+	if below < availableFrom {
+		return nil
+	}
+	if below > availableFrom {
+		if err := db.Put(dbutils.DatabaseInfoBucket, dbutils.SendersAvailableFromKey, dbutils.EncodeBlockNumber(below)); err != nil {
+			return fmt.Errorf("prune Senders: saving available from: %w", err)
+		}
+	}
+	mutation := db.NewBatch()
+	defer mutation.Rollback()
+	if err := db.Walk(dbutils.Senders, nil, 0, func(k, _ []byte) (bool, error) {
+		if binary.BigEndian.Uint64(k[:8]) >= below {
+			return false, nil
+		}
+		if err := mutation.Delete(dbutils.Senders, common.CopyBytes(k)); err != nil {
+			return false, err
+		}
+		if mutation.BatchSize() >= mutation.IdealBatchSize() {
+			if _, err := mutation.Commit(); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}); err != nil {
+		return fmt.Errorf("prune Senders: %w", err)
+	}
+	if _, err := mutation.Commit(); err != nil {
+		return fmt.Errorf("prune Senders: failed to write db commit: %w", err)
+	}
+	return nil
+}
+
 // WriteBody storea a block body into the database.
 func WriteBody(ctx context.Context, db DatabaseWriter, hash common.Hash, number uint64, body *types.Body) {
 	if common.IsCanceled(ctx) {
@@ -474,7 +560,11 @@ func ReadReceipts(db DatabaseReader, hash common.Hash, number uint64) types.Rece
 		log.Error("Missing body but have receipt", "hash", hash, "number", number)
 		return nil
 	}
-	senders := ReadSenders(db, hash, number)
+	senders, err := ReadSendersOrRecover(db, nil, hash, number)
+	if err != nil {
+		log.Error("Failed to read block senders", "hash", hash, "number", number, "err", err)
+		return nil
+	}
 	if err := receipts.DeriveFields(hash, number, body.Transactions, senders); err != nil {
 		log.Error("Failed to derive block receipts fields", "hash", hash, "number", number, "err", err)
 		return nil
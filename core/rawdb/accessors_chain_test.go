@@ -29,7 +29,9 @@ import (
 	"github.com/ledgerwatch/turbo-geth/common"
 	"github.com/ledgerwatch/turbo-geth/common/u256"
 	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/crypto"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/params"
 	"github.com/ledgerwatch/turbo-geth/rlp"
 )
 
@@ -354,6 +356,43 @@ func TestBlockReceiptStorage(t *testing.T) {
 	}
 }
 
+func TestReadReceiptsPrunedSenders(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	ctx := context.Background()
+
+	config := params.AllEthashProtocolChanges
+	genesisHash := common.Hash{0x01}
+	WriteCanonicalHash(db, genesisHash, 0)
+	WriteChainConfig(db, genesisHash, config)
+
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	create, err := types.SignTx(types.NewContractCreation(3, u256.Num0, 100000, u256.Num1, nil), types.NewEIP155Signer(config.ChainID), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := common.Hash{0x02}
+	WriteBody(ctx, db, hash, 1, &types.Body{Transactions: types.Transactions{create}})
+	WriteSenders(ctx, db, hash, 1, []common.Address{sender})
+	WriteReceipts(db, hash, 1, types.Receipts{{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 1}})
+
+	if err := PruneSenders(db, 2); err != nil {
+		t.Fatal(err)
+	}
+	if senders := ReadSenders(db, hash, 1); len(senders) != 0 {
+		t.Fatalf("senders not pruned: %v", senders)
+	}
+	// the sender of the contract creation is recovered to derive the contract address
+	rs := ReadReceipts(db, hash, 1)
+	if len(rs) != 1 {
+		t.Fatalf("unexpected receipts: %v", rs)
+	}
+	if want := crypto.CreateAddress(sender, 3); rs[0].ContractAddress != want {
+		t.Fatalf("contract address %x, want %x", rs[0].ContractAddress, want)
+	}
+}
+
 func checkReceiptsRLP(have, want types.Receipts) error {
 	if len(have) != len(want) {
 		return fmt.Errorf("receipts sizes mismatch: have %d, want %d", len(have), len(want))
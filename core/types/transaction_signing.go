@@ -35,18 +35,41 @@ var (
 	ErrInvalidChainId = errors.New("invalid chain id for signer")
 )
 
+// signerForks lists the forks changing the signer, latest first. Blocks before all of them use FrontierSigner.
+var signerForks = []struct {
+	block  func(config *params.ChainConfig) *big.Int
+	signer func(config *params.ChainConfig) Signer
+}{
+	{
+		block:  func(config *params.ChainConfig) *big.Int { return config.EIP155Block },
+		signer: func(config *params.ChainConfig) Signer { return NewEIP155Signer(config.ChainID) },
+	},
+	{
+		block:  func(config *params.ChainConfig) *big.Int { return config.HomesteadBlock },
+		signer: func(config *params.ChainConfig) Signer { return HomesteadSigner{} },
+	},
+}
+
 // MakeSigner returns a Signer based on the given chain config and block number.
 func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
-	var signer Signer
-	switch {
-	case config.IsEIP155(blockNumber):
-		signer = NewEIP155Signer(config.ChainID)
-	case config.IsHomestead(blockNumber):
-		signer = HomesteadSigner{}
-	default:
-		signer = FrontierSigner{}
+	for _, fork := range signerForks {
+		if block := fork.block(config); block != nil && blockNumber != nil && block.Cmp(blockNumber) <= 0 {
+			return fork.signer(config)
+		}
+	}
+	return FrontierSigner{}
+}
+
+// SignerForkBlocks returns the blocks at which MakeSigner may return another signer, unscheduled forks are skipped.
+// Callers caching the signer can keep it until the next of these blocks.
+func SignerForkBlocks(config *params.ChainConfig) []*big.Int {
+	blocks := make([]*big.Int, 0, len(signerForks))
+	for _, fork := range signerForks {
+		if block := fork.block(config); block != nil {
+			blocks = append(blocks, block)
+		}
 	}
-	return signer
+	return blocks
 }
 
 // SignTx signs the transaction using the given signer and private key
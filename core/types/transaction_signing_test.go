@@ -24,6 +24,7 @@ import (
 
 	"github.com/ledgerwatch/turbo-geth/common"
 	"github.com/ledgerwatch/turbo-geth/crypto"
+	"github.com/ledgerwatch/turbo-geth/params"
 	"github.com/ledgerwatch/turbo-geth/rlp"
 )
 
@@ -138,3 +139,34 @@ func TestChainId(t *testing.T) {
 		t.Error("expected no error")
 	}
 }
+
+func TestMakeSignerForks(t *testing.T) {
+	config := params.MainnetChainConfig
+	blocks := SignerForkBlocks(config)
+	if len(blocks) != 2 || blocks[0] != config.EIP155Block || blocks[1] != config.HomesteadBlock {
+		t.Fatalf("unexpected fork blocks: %v", blocks)
+	}
+	for _, tt := range []struct {
+		block  int64
+		signer Signer
+	}{
+		{0, FrontierSigner{}},
+		{config.HomesteadBlock.Int64() - 1, FrontierSigner{}},
+		{config.HomesteadBlock.Int64(), HomesteadSigner{}},
+		{config.EIP155Block.Int64() - 1, HomesteadSigner{}},
+		{config.EIP155Block.Int64(), NewEIP155Signer(config.ChainID)},
+	} {
+		if signer := MakeSigner(config, big.NewInt(tt.block)); !signer.Equal(tt.signer) {
+			t.Errorf("block %d: signer %T, want %T", tt.block, signer, tt.signer)
+		}
+	}
+
+	// unscheduled forks are skipped
+	noEIP155 := &params.ChainConfig{ChainID: big.NewInt(1), HomesteadBlock: big.NewInt(10)}
+	if blocks := SignerForkBlocks(noEIP155); len(blocks) != 1 || blocks[0].Int64() != 10 {
+		t.Errorf("unexpected fork blocks: %v", blocks)
+	}
+	if signer := MakeSigner(noEIP155, big.NewInt(1000)); !signer.Equal(HomesteadSigner{}) {
+		t.Errorf("signer %T, want HomesteadSigner", signer)
+	}
+}
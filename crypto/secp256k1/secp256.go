@@ -90,6 +90,16 @@ func NewContext() *Context {
 	return &Context{ctx}
 }
 
+// Destroy frees the underlying C context. Must be called only for contexts created by NewContext,
+// the context can't be used afterwards.
+func (c *Context) Destroy() {
+	if c.context == nil {
+		return
+	}
+	C.secp256k1_context_destroy(c.context)
+	c.context = nil
+}
+
 var (
 	ErrInvalidMsgLen       = errors.New("invalid message length, need 32 bytes")
 	ErrInvalidSignatureLen = errors.New("invalid signature length")
@@ -18,20 +18,18 @@
 package eth
 
 import (
-	"crypto/tls"
-	"crypto/x509"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"math/big"
 	"os"
 	"reflect"
 	"runtime"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	ethereum "github.com/ledgerwatch/turbo-geth"
-	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc"
 
 	"github.com/ledgerwatch/turbo-geth/accounts"
 	"github.com/ledgerwatch/turbo-geth/common"
@@ -47,6 +45,7 @@ import (
 	"github.com/ledgerwatch/turbo-geth/eth/downloader"
 	"github.com/ledgerwatch/turbo-geth/eth/filters"
 	"github.com/ledgerwatch/turbo-geth/eth/gasprice"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
 	"github.com/ledgerwatch/turbo-geth/ethdb/remote/remotedbserver"
 	"github.com/ledgerwatch/turbo-geth/event"
@@ -63,6 +62,10 @@ import (
 	"github.com/ledgerwatch/turbo-geth/rpc"
 )
 
+// privateAPIShutdownTimeout is how long Stop waits for in-flight private API
+// streams before closing them forcibly.
+const privateAPIShutdownTimeout = 5 * time.Second
+
 // Ethereum implements the Ethereum full node service.
 type Ethereum struct {
 	config *Config
@@ -77,6 +80,8 @@ type Ethereum struct {
 	chainDb *ethdb.ObjectDatabase // Block chain database
 	chainKV ethdb.KV              // Same as chainDb, but different interface
 
+	privateAPI *grpc.Server
+
 	eventMux       *event.TypeMux
 	engine         consensus.Engine
 	accountManager *accounts.Manager
@@ -230,40 +235,32 @@ func New(stack *node.Node, config *Config) (*Ethereum, error) {
 	eth.txPool = core.NewTxPool(config.TxPool, chainConfig, chainDb, txCacher)
 
 	if stack.Config().PrivateApiAddr != "" {
+		grpcCfg := remotedbserver.DefaultGrpcConfig(stack.Config().PrivateApiAddr)
+		grpcCfg.AuthToken = stack.Config().PrivateApiToken
+		if stack.Config().PrivateApiMaxStreams > 0 {
+			grpcCfg.MaxConcurrentStreams = stack.Config().PrivateApiMaxStreams
+		}
+		grpcCfg.RateLimit, grpcCfg.RateBurst = stack.Config().PrivateApiRateLimit, stack.Config().PrivateApiRateBurst
+		grpcCfg.AllowedBuckets = stack.Config().PrivateApiBuckets
+		if stack.Config().PrivateApiMaxReadTxs > 0 {
+			grpcCfg.MaxReadTxs = stack.Config().PrivateApiMaxReadTxs
+		}
+		if stack.Config().PrivateApiMaxReadTxsWait != 0 {
+			grpcCfg.MaxReadTxsWait = stack.Config().PrivateApiMaxReadTxsWait
+		}
+		if stack.Config().PrivateApiStaleClientTimeout != 0 {
+			grpcCfg.StaleClientTimeout = stack.Config().PrivateApiStaleClientTimeout
+		}
 		if stack.Config().TLSConnection {
-			// load peer cert/key, ca cert
-			var creds credentials.TransportCredentials
-
-			if stack.Config().TLSCACert != "" {
-				var peerCert tls.Certificate
-				var caCert []byte
-				peerCert, err = tls.LoadX509KeyPair(stack.Config().TLSCertFile, stack.Config().TLSKeyFile)
-				if err != nil {
-					log.Error("load peer cert/key error:%v", err)
-					return nil, err
-				}
-				caCert, err = ioutil.ReadFile(stack.Config().TLSCACert)
-				if err != nil {
-					log.Error("read ca cert file error:%v", err)
-					return nil, err
-				}
-				caCertPool := x509.NewCertPool()
-				caCertPool.AppendCertsFromPEM(caCert)
-				creds = credentials.NewTLS(&tls.Config{
-					Certificates: []tls.Certificate{peerCert},
-					ClientCAs:    caCertPool,
-					ClientAuth:   tls.RequireAndVerifyClientCert,
-				})
-			} else {
-				creds, err = credentials.NewServerTLSFromFile(stack.Config().TLSCertFile, stack.Config().TLSKeyFile)
-			}
-
+			tlsCreds, err := remotedbserver.TLS(stack.Config().TLSCertFile, stack.Config().TLSKeyFile, stack.Config().TLSCACert)
 			if err != nil {
 				return nil, err
 			}
-			remotedbserver.StartGrpc(chainDb.KV(), eth, stack.Config().PrivateApiAddr, &creds)
-		} else {
-			remotedbserver.StartGrpc(chainDb.KV(), eth, stack.Config().PrivateApiAddr, nil)
+			grpcCfg.Creds = &tlsCreds
+		}
+		eth.privateAPI, err = remotedbserver.StartGrpc(chainDb.KV(), eth, grpcCfg)
+		if err != nil {
+			return nil, err
 		}
 	}
 
@@ -271,7 +268,17 @@ func New(stack *node.Node, config *Config) (*Ethereum, error) {
 	if checkpoint == nil {
 		//checkpoint = params.TrustedCheckpoints[genesisHash]
 	}
-	if eth.protocolManager, err = NewProtocolManager(chainConfig, checkpoint, config.SyncMode, config.NetworkID, eth.eventMux, eth.txPool, eth.engine, eth.blockchain, chainDb, config.Whitelist, config.StagedSync); err != nil {
+	stagedSync := config.StagedSync
+	if stagedSync == nil {
+		// a custom StagedSync comes with its own senders config
+		stagedSync = stagedsync.New(stagedsync.DefaultStages(), stagedsync.DefaultUnwindOrder())
+		stagedSync.Senders.NumOfGoroutines = config.SendersWorkers
+		stagedSync.Senders.MaxHeapAlloc = config.SendersMaxHeapAlloc
+		stagedSync.Senders.LockOSThread = config.SendersLockOSThread
+		stagedSync.Senders.MaxTempFilesSize = config.SendersMaxTempFilesSize
+		stagedSync.Senders.SkipInvalidChainID = config.SendersSkipInvalidChainID
+	}
+	if eth.protocolManager, err = NewProtocolManager(chainConfig, checkpoint, config.SyncMode, config.NetworkID, eth.eventMux, eth.txPool, eth.engine, eth.blockchain, chainDb, config.Whitelist, stagedSync); err != nil {
 		return nil, err
 	}
 	eth.miner = miner.New(eth, &config.Miner, chainConfig, eth.EventMux(), eth.engine, eth.isLocalBlock)
@@ -718,6 +725,19 @@ func (s *Ethereum) Stop() error {
 	if s.txPool != nil {
 		s.txPool.Stop()
 	}
+	if s.privateAPI != nil {
+		shutdownDone := make(chan struct{})
+		go func() {
+			defer close(shutdownDone)
+			s.privateAPI.GracefulStop()
+		}()
+		select {
+		case <-time.After(privateAPIShutdownTimeout):
+			log.Warn("Private RPC server did not stop gracefully, forcing shutdown")
+			s.privateAPI.Stop()
+		case <-shutdownDone:
+		}
+	}
 	//s.chainDb.Close()
 	return nil
 }
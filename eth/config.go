@@ -127,6 +127,13 @@ type Config struct {
 	StorageMode ethdb.StorageMode
 	Hdd         bool // Whether to use warm up strategy to deal with the high latency of HDD
 
+	// Senders recovery options, see stagedsync.Stage3Config. Ignored if StagedSync is set, it has its own Senders
+	SendersWorkers            int    // number of recoverer goroutines, 0 means one per available crypto context
+	SendersMaxHeapAlloc       uint64 // allocated heap in bytes above which reading bodies pauses, 0 means no limit
+	SendersLockOSThread       bool   // lock every recoverer goroutine to its own OS thread
+	SendersMaxTempFilesSize   int    // collected senders in bytes after which they are loaded into the db, 0 means no limit, ignored within one transaction
+	SendersSkipInvalidChainID bool   // store the zero address as sender of transactions signed for another chain
+
 	// DownloadOnly is set when the node does not need to process the blocks, but simply
 	// download them
 	DownloadOnly        bool
@@ -2,8 +2,6 @@ package stagedsync
 
 import (
 	"context"
-	"runtime"
-	"time"
 
 	"github.com/ledgerwatch/turbo-geth/consensus"
 	"github.com/ledgerwatch/turbo-geth/core"
@@ -48,24 +46,11 @@ func InsertBlockInStages(db ethdb.Database, config *params.ChainConfig, engine c
 		return err
 	}
 	// Stage 4
-	const batchSize = 10000
-	const blockSize = 4096
-	n := runtime.NumCPU()
-
-	cfg := Stage3Config{
-		BatchSize:       batchSize,
-		BlockSize:       blockSize,
-		BufferSize:      (blockSize * 10 / 20) * 10000, // 20*4096
-		StartTrace:      false,
-		Prof:            false,
-		NumOfGoroutines: n,
-		ReadChLen:       4,
-		Now:             time.Now(),
-	}
-	if err := SpawnRecoverSendersStage(cfg, &StageState{
+	cfg := DefaultStage3Config()
+	if err := SpawnRecoverSendersStage(context.Background(), cfg, &StageState{
 		Stage:       stages.Senders,
 		BlockNumber: num - 1,
-	}, db, config, 0, "", nil); err != nil {
+	}, db, config, 0, ""); err != nil {
 		return err
 	}
 
@@ -99,8 +99,16 @@ func SpawnExecuteBlocksStage(s *StageState, stateDB ethdb.Database, chainConfig
 		if block == nil {
 			break
 		}
-		senders := rawdb.ReadSenders(tx, blockHash, blockNum)
-		block.Body().SendersToTxs(senders)
+		senders, err := rawdb.ReadSendersOrRecover(tx, chainConfig, blockHash, blockNum)
+		if err != nil {
+			return fmt.Errorf("sync Execute: %w", err)
+		}
+		if err := applySenders(blockNum, block.Body(), senders); err != nil {
+			return fmt.Errorf("sync Execute: %w", err)
+		}
+		if err := checkZeroSenders(blockNum, senders); err != nil {
+			return fmt.Errorf("sync Execute: %w", err)
+		}
 
 		if warmup {
 			log.Info("Running a warmup...")
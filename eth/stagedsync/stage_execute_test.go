@@ -2,13 +2,23 @@ package stagedsync
 
 import (
 	"context"
-	"github.com/stretchr/testify/require"
+	"errors"
+	"math/big"
 	"testing"
 
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/turbo-geth/common"
 	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/consensus/ethash"
 	"github.com/ledgerwatch/turbo-geth/core"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/core/vm"
+	"github.com/ledgerwatch/turbo-geth/crypto"
 	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/params"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUnwindExecutionStagePlainStatic(t *testing.T) {
@@ -129,3 +139,95 @@ func TestUnwindExecutionStagePlainWithCodeChanges(t *testing.T) {
 
 	compareCurrentState(t, db1, db2, dbutils.PlainStateBucket, dbutils.PlainContractCodeBucket)
 }
+
+func TestExecuteBlocksWithPrunedSenders(t *testing.T) {
+	var (
+		db     = ethdb.NewMemDatabase()
+		genDb  = ethdb.NewMemDatabase()
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		config = params.TestChainConfig
+		engine = ethash.NewFaker()
+		signer = types.MakeSigner(config, big.NewInt(1))
+	)
+	defer db.Close()
+	defer genDb.Close()
+	gspec := &core.Genesis{Config: config, Alloc: core.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}}}
+	genesis := gspec.MustCommit(genDb)
+	blocks, _, err := core.GenerateChain(config, genesis, engine, genDb, 3, func(i int, gen *core.BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(gen.TxNonce(addr), common.Address{1}, uint256.NewInt().SetUint64(1), params.TxGas, nil, nil), signer, key)
+		require.NoError(t, err)
+		gen.AddTx(tx)
+	}, false /* intermediateHashes */)
+	require.NoError(t, err)
+
+	gspec.MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, config, engine, vm.Config{}, nil, core.NewTxSenderCacher(1))
+	require.NoError(t, err)
+	defer chain.Stop()
+
+	for _, block := range blocks {
+		num := block.NumberU64()
+		_, _, err = InsertHeaderChain(db, []*types.Header{block.Header()}, config, engine, 1)
+		require.NoError(t, err)
+		require.NoError(t, stages.SaveStageProgress(db, stages.Headers, num, nil))
+		require.NoError(t, SpawnBlockHashStage(&StageState{Stage: stages.BlockHashes, BlockNumber: num - 1}, db, "", nil))
+		_, err = chain.InsertBodyChain(context.Background(), []*types.Block{block})
+		require.NoError(t, err)
+		require.NoError(t, stages.SaveStageProgress(db, stages.Bodies, num, nil))
+		require.NoError(t, SpawnRecoverSendersStage(context.Background(), DefaultStage3Config(), &StageState{Stage: stages.Senders, BlockNumber: num - 1}, db, config, 0, ""))
+
+		// senders of the block are gone before it's executed, execution recovers them from the body
+		require.NoError(t, rawdb.PruneSenders(db, num+1))
+		require.Empty(t, rawdb.ReadSenders(db, block.Hash(), num))
+		require.NoError(t, SpawnExecuteBlocksStage(&StageState{Stage: stages.Execution, BlockNumber: num - 1}, db, config, chain, chain.GetVMConfig(), 0, nil, true, false, nil))
+	}
+	progress, _, err := stages.GetStageProgress(db, stages.Execution)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), progress)
+}
+
+func TestExecuteBlocksRefusesZeroSender(t *testing.T) {
+	var (
+		db     = ethdb.NewMemDatabase()
+		genDb  = ethdb.NewMemDatabase()
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		config = params.TestChainConfig
+		engine = ethash.NewFaker()
+		signer = types.MakeSigner(config, big.NewInt(1))
+	)
+	defer db.Close()
+	defer genDb.Close()
+	gspec := &core.Genesis{Config: config, Alloc: core.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}}}
+	genesis := gspec.MustCommit(genDb)
+	blocks, _, err := core.GenerateChain(config, genesis, engine, genDb, 1, func(i int, gen *core.BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(gen.TxNonce(addr), common.Address{1}, uint256.NewInt().SetUint64(1), params.TxGas, nil, nil), signer, key)
+		require.NoError(t, err)
+		gen.AddTx(tx)
+	}, false /* intermediateHashes */)
+	require.NoError(t, err)
+
+	gspec.MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, config, engine, vm.Config{}, nil, core.NewTxSenderCacher(1))
+	require.NoError(t, err)
+	defer chain.Stop()
+
+	block := blocks[0]
+	_, _, err = InsertHeaderChain(db, []*types.Header{block.Header()}, config, engine, 1)
+	require.NoError(t, err)
+	require.NoError(t, stages.SaveStageProgress(db, stages.Headers, 1, nil))
+	require.NoError(t, SpawnBlockHashStage(&StageState{Stage: stages.BlockHashes}, db, "", nil))
+	_, err = chain.InsertBodyChain(context.Background(), []*types.Block{block})
+	require.NoError(t, err)
+	require.NoError(t, stages.SaveStageProgress(db, stages.Bodies, 1, nil))
+
+	// the sender was skipped by the senders stage, see Stage3Config.SkipInvalidChainID
+	rawdb.WriteSenders(context.Background(), db, block.Hash(), 1, []common.Address{{}})
+	require.NoError(t, stages.SaveStageProgress(db, stages.Senders, 1, nil))
+	err = SpawnExecuteBlocksStage(&StageState{Stage: stages.Execution}, db, config, chain, chain.GetVMConfig(), 0, nil, true, false, nil)
+	require.True(t, errors.Is(err, ErrZeroSender), "unexpected error %v", err)
+	progress, _, err := stages.GetStageProgress(db, stages.Execution)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), progress)
+}
@@ -2,13 +2,17 @@ package stagedsync
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
 	"os"
+	"runtime"
 	"runtime/pprof"
 	"runtime/trace"
+	"sort"
 	"sync"
 	"time"
 
@@ -22,36 +26,132 @@ import (
 	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
 	"github.com/ledgerwatch/turbo-geth/log"
+	"github.com/ledgerwatch/turbo-geth/metrics"
 	"github.com/ledgerwatch/turbo-geth/params"
 	"github.com/ledgerwatch/turbo-geth/rlp"
 )
 
+var (
+	sendersBlocksMeter    = metrics.NewRegisteredMeter("stages/senders/blocks", nil)
+	sendersTxsMeter       = metrics.NewRegisteredMeter("stages/senders/txs", nil)
+	sendersCollectedMeter = metrics.NewRegisteredMeter("stages/senders/collected", nil) // bytes passed to the etl collector
+	sendersPendingGauge   = metrics.NewRegisteredGauge("stages/senders/pending", nil)   // recovered blocks waiting to be collected
+
+	// live throughput for controllers tuning the recovery, meters only expose averages over minutes
+	sendersBlocksRateGauge = metrics.NewRegisteredGaugeFloat64("stages/senders/blocks_per_second", nil)
+	sendersTxsRateGauge    = metrics.NewRegisteredGaugeFloat64("stages/senders/txs_per_second", nil)
+
+	// staged sync runs every cycle near the tip in one transaction, so the warning is logged only once
+	tempFilesLimitIgnored sync.Once
+)
+
+// Stage3Config configures the senders recovery.
+//
+// BatchSize bounds how many blocks may wait in the channels between the body reader, the recoverers and
+// the collector. Blocks are recovered out of order, so a bigger window keeps all recoverers busy when
+// a single block is slow, at the price of holding more decoded bodies in memory.
+//
+// BufferSize is measured in bytes of collected senders, not in blocks, so the flushes stay the same size
+// whether blocks are empty or full. A bigger buffer means fewer and larger temporary files and a faster
+// load, but more memory. Runs smaller than the buffer never touch the disk.
+//
+// MaxTempFilesSize bounds the disk usage of the temporary files: once the collected senders reach it, they are
+// loaded into the db and the collection starts over. Buffered senders count too, so the files stay below it.
+// The limit is disabled when the stage runs within an external transaction: the body reader walks that
+// transaction while the stage runs, so the senders can't be loaded into it before the end. Staged sync uses
+// an external transaction only for cycles shorter than 1024 blocks, whose senders fit in memory anyway; the
+// initial sync runs without one, so the limit applies there.
+//
+// MaxHeapAlloc pauses the body reader while the allocated heap is above it, so the decoded bodies don't
+// pile up when the recoverers fall behind. The reader resumes when the heap drops or the recoverers run
+// out of queued blocks, whichever comes first, so a ceiling below the baseline usage only slows the stage down.
+//
+// LockOSThread wires every recoverer to its own OS thread, so the thread keeps its crypto context warm in the
+// CPU caches instead of the scheduler moving recoverers across cores or NUMA sockets. The OS still decides
+// where the threads run, and a locked thread can't run other goroutines, so it only pays off on big machines
+// where recoverers get about one core each.
 type Stage3Config struct {
-	BatchSize       int
-	BlockSize       int
-	BufferSize      int
+	BatchSize       int // capacity of the channels between the body reader, recoverers and collector
+	BufferSize      int // size in bytes of the collector buffer, it is flushed to a temporary file when full
 	StartTrace      bool
 	Prof            bool
 	ToProcess       int
-	NumOfGoroutines int
-	ReadChLen       int
-	Now             time.Time
+	NumOfGoroutines int    // number of recoverer goroutines, 0 means one per available crypto context
+	MaxHeapAlloc    uint64 // allocated heap in bytes above which the body reader pauses, 0 means no limit
+	LockOSThread    bool   // lock every recoverer goroutine to its own OS thread
+	// MaxTempFilesSize - collected senders in bytes after which they are loaded into the db, 0 means no limit
+	MaxTempFilesSize int
+	// SkipInvalidChainID makes the stage log transactions signed for another chain and store the zero address
+	// as their sender, instead of failing. The Execution stage refuses blocks with a zero sender, see ErrZeroSender,
+	// so the option only lets the stages before Execution run. Keep it off for consensus critical runs.
+	SkipInvalidChainID bool
+	// Verify makes the stage recover senders of already processed blocks and compare them to the stored ones,
+	// instead of writing. Mismatches are logged and reported as SendersMismatchError, the stage progress isn't changed.
+	// Blocks after StageState.BlockNumber up to the Senders progress are checked, so within staged sync, where
+	// BlockNumber is the progress itself, nothing is checked: run it with an explicit start, see `integration verify_senders`.
+	Verify bool
+	// Logger receives all the messages of the stage, tagged with the stage name. Nil means the root logger.
+	Logger log.Logger
+	Now    time.Time
+}
+
+// ErrMissingBody - a canonical block below the bodies stage progress has no body, the senders stage can't skip it
+var ErrMissingBody = errors.New("missing block body")
+
+// ErrSenderRecoveryPanic - recovery of a transaction sender panicked, e.g. on a malformed signature
+var ErrSenderRecoveryPanic = errors.New("sender recovery panicked")
+
+// SendersMismatchError - the verify mode found stored senders which differ from the recovered ones
+type SendersMismatchError struct {
+	Mismatches int
+}
+
+func (e *SendersMismatchError) Error() string {
+	return fmt.Sprintf("stored senders of %d transactions differ from the recovered ones", e.Mismatches)
 }
 
-func SpawnRecoverSendersStage(cfg Stage3Config, s *StageState, db ethdb.Database, config *params.ChainConfig, toBlock uint64, datadir string, quitCh <-chan struct{}) error {
+// DefaultStage3Config returns the senders stage configuration shared by all callers,
+// they may override single fields before passing it to SpawnRecoverSendersStage.
+func DefaultStage3Config() Stage3Config {
+	return Stage3Config{
+		BatchSize:  10000,
+		BufferSize: etl.BufferOptimalSize,
+		Now:        time.Now(),
+	}
+}
+
+func SpawnRecoverSendersStage(ctx context.Context, cfg Stage3Config, s *StageState, db ethdb.Database, config *params.ChainConfig, toBlock uint64, datadir string) error {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = log.Root()
+	}
+	logger = logger.New("stage", "senders")
 	prevStageProgress, _, errStart := stages.GetStageProgress(db, stages.Bodies)
 	if errStart != nil {
 		return errStart
 	}
 	var to = prevStageProgress
+	var verifyFrom uint64
+	if cfg.Verify {
+		// only blocks with stored senders can be verified, pruned ones are recovered but not compared
+		if to, _, errStart = stages.GetStageProgress(db, stages.Senders); errStart != nil {
+			return errStart
+		}
+		if verifyFrom, errStart = rawdb.SendersAvailableFrom(db); errStart != nil {
+			return errStart
+		}
+	}
 	if toBlock > 0 {
-		to = min(prevStageProgress, toBlock)
+		to = min(to, toBlock)
 	}
 	if to <= s.BlockNumber {
 		s.Done()
 		return nil
 	}
-	log.Info("Senders recovery", "from", s.BlockNumber, "to", to)
+	logger.Info("Senders recovery", "from", s.BlockNumber, "to", to)
+	// stops the body reader and the recoverers as soon as the stage returns, e.g. on the first recovery error
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	if cfg.StartTrace {
 		filePath := fmt.Sprintf("trace_%d_%d_%d.out", cfg.Now.Day(), cfg.Now.Hour(), cfg.Now.Minute())
@@ -71,12 +171,12 @@ func SpawnRecoverSendersStage(cfg Stage3Config, s *StageState, db ethdb.Database
 	if cfg.Prof {
 		f2, err := os.Create(fmt.Sprintf("cpu_%d_%d_%d.prof", cfg.Now.Day(), cfg.Now.Hour(), cfg.Now.Minute()))
 		if err != nil {
-			log.Error("could not create CPU profile", "error", err)
+			logger.Error("could not create CPU profile", "error", err)
 			return err
 		}
 		defer f2.Close()
 		if err = pprof.StartCPUProfile(f2); err != nil {
-			log.Error("could not start CPU profile", "error", err)
+			logger.Error("could not start CPU profile", "error", err)
 			return err
 		}
 	}
@@ -85,7 +185,7 @@ func SpawnRecoverSendersStage(cfg Stage3Config, s *StageState, db ethdb.Database
 	currentHeaderIdx := uint64(0)
 
 	if err := db.Walk(dbutils.HeaderPrefix, dbutils.EncodeBlockNumber(s.BlockNumber+1), 0, func(k, v []byte) (bool, error) {
-		if err := common.Stopped(quitCh); err != nil {
+		if err := common.ContextStopped(ctx); err != nil {
 			return false, err
 		}
 
@@ -104,13 +204,19 @@ func SpawnRecoverSendersStage(cfg Stage3Config, s *StageState, db ethdb.Database
 	}); err != nil {
 		return err
 	}
-	log.Info("Sync (Senders): Reading canonical hashes complete", "hashes", len(canonical))
+	logger.Info("Sync (Senders): Reading canonical hashes complete", "hashes", len(canonical))
 
 	jobs := make(chan *senderRecoveryJob, cfg.BatchSize)
+	ceiling := &heapCeiling{limit: cfg.MaxHeapAlloc, heapAlloc: readHeapAlloc}
+	// readErr is set by the body reader before it closes jobs, so it can be read once out is closed
+	var readErr error
 	go func() {
 		defer close(jobs)
-		if err := db.Walk(dbutils.BlockBodyPrefix, dbutils.EncodeBlockNumber(s.BlockNumber+1), 0, func(k, v []byte) (bool, error) {
-			if err := common.Stopped(quitCh); err != nil {
+		var read int
+		next := s.BlockNumber + 1 // the canonical block whose body is expected next
+		stopped := false
+		if readErr = db.Walk(dbutils.BlockBodyPrefix, dbutils.EncodeBlockNumber(s.BlockNumber+1), 0, func(k, v []byte) (bool, error) {
+			if err := common.ContextStopped(ctx); err != nil {
 				return false, err
 			}
 
@@ -124,6 +230,10 @@ func SpawnRecoverSendersStage(cfg Stage3Config, s *StageState, db ethdb.Database
 				// non-canonical case
 				return true, nil
 			}
+			if blockNumber != next {
+				return false, fmt.Errorf("%w: block %d, bodies progress %d", ErrMissingBody, next, to)
+			}
+			next++
 
 			data := make([]byte, len(v))
 			copy(data, v)
@@ -132,7 +242,7 @@ func SpawnRecoverSendersStage(cfg Stage3Config, s *StageState, db ethdb.Database
 				if blockNumber == uint64(cfg.ToProcess) {
 					// Flush the profiler
 					pprof.StopCPUProfile()
-					//common.SafeClose(quitCh)
+					stopped = true
 					return false, nil
 				}
 			}
@@ -142,82 +252,431 @@ func SpawnRecoverSendersStage(cfg Stage3Config, s *StageState, db ethdb.Database
 				return false, err
 			}
 
-			jobs <- &senderRecoveryJob{bodyRlp: bodyRlp, blockNumber: blockNumber, index: int(blockNumber - s.BlockNumber - 1)}
+			if read++; read%heapCheckEvery == 0 {
+				if err := ceiling.wait(ctx, func() int { return len(jobs) }); err != nil {
+					return false, err
+				}
+			}
+			select {
+			case jobs <- &senderRecoveryJob{bodyRlp: bodyRlp, blockNumber: blockNumber, index: int(blockNumber - s.BlockNumber - 1)}:
+			case <-ctx.Done():
+				return false, ctx.Err()
+			}
 
 			return true, nil
-		}); err != nil {
-			log.Error("walking over the block bodies", "error", err)
+		}); readErr != nil {
+			return
+		}
+		if !stopped && next <= to {
+			readErr = fmt.Errorf("%w: block %d, bodies progress %d", ErrMissingBody, next, to)
 		}
 	}()
 
+	numOfGoroutines := cfg.NumOfGoroutines
+	if numOfGoroutines <= 0 {
+		numOfGoroutines = secp256k1.NumOfContexts()
+	}
+	cryptoContexts, releaseCryptoContexts := newCryptoContexts(numOfGoroutines)
+
 	out := make(chan *senderRecoveryJob, cfg.BatchSize)
+	// senders buffers go back to the recoverers once the collector copied them
+	free := make(chan []byte, cfg.BatchSize)
 	wg := new(sync.WaitGroup)
-	wg.Add(cfg.NumOfGoroutines)
-	for i := 0; i < cfg.NumOfGoroutines; i++ {
+	wg.Add(numOfGoroutines)
+	for i := 0; i < numOfGoroutines; i++ {
 		go func(threadNo int) {
 			defer wg.Done()
-			// each goroutine gets it's own crypto context to make sure they are really parallel
-			recoverSenders(secp256k1.ContextForThread(threadNo), config, jobs, out, quitCh)
+			if cfg.LockOSThread {
+				runtime.LockOSThread()
+				defer runtime.UnlockOSThread()
+			}
+			recoverSenders(ctx, cryptoContexts[threadNo], config, cfg.SkipInvalidChainID, jobs, out, free)
 		}(i)
 	}
-	log.Info("Sync (Senders): Started recoverer goroutines", "numOfGoroutines", cfg.NumOfGoroutines)
+	logger.Info("Sync (Senders): Started recoverer goroutines", "numOfGoroutines", numOfGoroutines)
 	go func() {
 		wg.Wait()
+		releaseCryptoContexts()
 		close(out)
 	}()
 
-	collector := etl.NewCollector(datadir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = etl.BufferOptimalSize
+	}
+	collector := etl.NewCollector(datadir, etl.NewSortableBuffer(bufferSize))
+	load := func() error {
+		return collector.Load(db,
+			dbutils.Senders,
+			func(k []byte, value []byte, _ etl.State, next etl.LoadNextFunc) error {
+				index := int(binary.BigEndian.Uint32(k))
+				return next(k, dbutils.BlockBodyKey(s.BlockNumber+uint64(index)+1, canonical[index]), value)
+			},
+			etl.TransformArgs{
+				Quit: ctx.Done(),
+				LogDetailsExtract: func(k, v []byte) (additionalLogArguments []interface{}) {
+					return []interface{}{"block", binary.BigEndian.Uint64(k)}
+				},
+				LogDetailsLoad: func(k, v []byte) (additionalLogArguments []interface{}) {
+					return []interface{}{"block", binary.BigEndian.Uint64(k)}
+				},
+			},
+		)
+	}
+	maxTempFilesSize := cfg.MaxTempFilesSize
+	if hasTx, ok := db.(ethdb.HasTx); ok && hasTx.Tx() != nil && maxTempFilesSize > 0 {
+		tempFilesLimitIgnored.Do(func() {
+			logger.Warn("Senders recovery: limit of temporary files is disabled within external transaction, senders are loaded at the end", "limit", maxTempFilesSize)
+		})
+		maxTempFilesSize = 0
+	}
+	var collected int // bytes collected since the last load
 	logEvery := time.NewTicker(30 * time.Second)
 	defer logEvery.Stop()
+	var skipped, mismatches, pruned int
+	progress := &sendersProgress{from: s.BlockNumber, to: to, prevBlock: s.BlockNumber, prevTime: time.Now()}
+	throughputEvery := time.NewTicker(throughputInterval)
+	defer throughputEvery.Stop()
+	throughput := newSendersThroughput(time.Now())
+	defer func() { throughput.stop(time.Now()) }()
 	for j := range out {
 		if j.err != nil {
-			return j.err
+			var txErr *TxSenderError
+			if errors.As(j.err, &txErr) {
+				logger.Error("Senders recovery: transaction poisons the block", "block", txErr.BlockNumber, "txIndex", txErr.TxIndex, "tx", txErr.TxHash, "error", txErr.Err)
+			}
+			return fmt.Errorf("sync Senders: block %d: %w", j.blockNumber, j.err)
 		}
-		if err := common.Stopped(quitCh); err != nil {
+		if err := common.ContextStopped(ctx); err != nil {
 			return err
 		}
-		k := make([]byte, 4)
 		select {
 		default:
 		case <-logEvery.C:
-			log.Info("Senders recovery", "block", j.index)
+			percent, eta := progress.update(j.blockNumber, time.Now())
+			logger.Info("Senders recovery", "block", j.blockNumber, "progress", fmt.Sprintf("%.2f%%", percent), "eta", eta)
+		case now := <-throughputEvery.C:
+			throughput.publish(now)
+		}
+		for _, txErr := range j.skipped {
+			logger.Warn("Senders recovery: skipped transaction with invalid chain id, block needs inspection", "block", txErr.BlockNumber, "txIndex", txErr.TxIndex, "tx", txErr.TxHash)
+		}
+		skipped += len(j.skipped)
+		sendersBlocksMeter.Mark(1)
+		throughput.add(len(j.senders) / common.AddressLength)
+		if cfg.Verify {
+			if j.blockNumber < verifyFrom {
+				pruned++
+			} else {
+				mismatches += verifySenders(logger, db, j, canonical[j.index])
+			}
+			recycleSendersBuffer(free, j.senders)
+			continue
+		}
+		if len(j.senders) == 0 {
+			// empty values are not stored by the collector anyway
+			continue
 		}
+		k := make([]byte, 4)
 		binary.BigEndian.PutUint32(k, uint32(j.index))
 		if err := collector.Collect(k, j.senders); err != nil {
 			return err
 		}
+		sendersTxsMeter.Mark(int64(len(j.senders) / common.AddressLength))
+		sendersCollectedMeter.Mark(int64(len(k) + len(j.senders)))
+		sendersPendingGauge.Update(int64(len(out)))
+		collected += len(k) + len(j.senders)
+		recycleSendersBuffer(free, j.senders)
+		if maxTempFilesSize > 0 && collected >= maxTempFilesSize {
+			logger.Info("Senders recovery: temporary files reached the limit, loading collected senders", "size", common.StorageSize(collected))
+			if err := load(); err != nil {
+				return err
+			}
+			collector = etl.NewCollector(datadir, etl.NewSortableBuffer(bufferSize))
+			collected = 0
+		}
 	}
-	loadFunc := func(k []byte, value []byte, _ etl.State, next etl.LoadNextFunc) error {
-		index := int(binary.BigEndian.Uint32(k))
-		return next(k, dbutils.BlockBodyKey(s.BlockNumber+uint64(index)+1, canonical[index]), value)
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	if err := collector.Load(db,
-		dbutils.Senders,
-		loadFunc,
-		etl.TransformArgs{
-			Quit: quitCh,
-			LogDetailsExtract: func(k, v []byte) (additionalLogArguments []interface{}) {
-				return []interface{}{"block", binary.BigEndian.Uint64(k)}
-			},
-			LogDetailsLoad: func(k, v []byte) (additionalLogArguments []interface{}) {
-				return []interface{}{"block", binary.BigEndian.Uint64(k)}
-			},
-		},
-	); err != nil {
+	// the body reader is done once out is closed
+	if readErr != nil {
+		return fmt.Errorf("sync Senders: reading bodies: %w", readErr)
+	}
+	if ceiling.pauses > 0 {
+		logger.Info("Senders recovery: body reader was paused by the heap ceiling", "times", ceiling.pauses, "ceiling", cfg.MaxHeapAlloc)
+	}
+	if skipped > 0 {
+		logger.Warn("Senders recovery: transactions with invalid chain id were skipped", "amount", skipped)
+	}
+	if cfg.Verify {
+		logger.Info("Senders verification done", "from", s.BlockNumber, "to", to, "mismatches", mismatches, "pruned", pruned)
+		if mismatches > 0 {
+			return &SendersMismatchError{Mismatches: mismatches}
+		}
+		return nil
+	}
+	if err := load(); err != nil {
 		return err
 	}
 	return s.DoneAndUpdate(db, to)
 }
 
+// heapCheckEvery - how many bodies the reader passes between heap checks, reading the memory stats stops the world
+const heapCheckEvery = 128
+
+// heapPollInterval - how often a paused body reader checks the heap again
+const heapPollInterval = 10 * time.Millisecond
+
+func readHeapAlloc() uint64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+// heapCeiling holds the body reader back while the allocated heap is above the limit
+type heapCeiling struct {
+	limit     uint64 // 0 means no limit
+	heapAlloc func() uint64
+	pauses    int // times the reader had to wait
+}
+
+// wait blocks while the heap is above the limit and the recoverers still have queued blocks,
+// without queued blocks the heap can't be freed by the recoverers, so waiting longer is pointless
+func (c *heapCeiling) wait(ctx context.Context, queued func() int) error {
+	if c.limit == 0 || c.heapAlloc() <= c.limit {
+		return nil
+	}
+	c.pauses++
+	poll := time.NewTicker(heapPollInterval)
+	defer poll.Stop()
+	for queued() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-poll.C:
+		}
+		if c.heapAlloc() <= c.limit {
+			return nil
+		}
+	}
+	return nil
+}
+
+// verifySenders compares the recovered senders of the block to the stored ones and returns the number of mismatches
+func verifySenders(logger log.Logger, db rawdb.DatabaseReader, j *senderRecoveryJob, hash common.Hash) int {
+	stored := rawdb.ReadSenders(db, hash, j.blockNumber)
+	recovered := len(j.senders) / common.AddressLength
+	mismatches := 0
+	for i := 0; i < recovered || i < len(stored); i++ {
+		var want, got common.Address
+		if i < recovered {
+			want = common.BytesToAddress(j.senders[i*common.AddressLength : (i+1)*common.AddressLength])
+		}
+		if i < len(stored) {
+			got = stored[i]
+		}
+		if i >= recovered || i >= len(stored) || want != got {
+			logger.Warn("Senders verification: stored sender differs", "block", j.blockNumber, "txIndex", i, "stored", got, "recovered", want)
+			mismatches++
+		}
+	}
+	return mismatches
+}
+
+// sendersProgress estimates how far the senders recovery is and when it is going to finish.
+// Speed is smoothed with an exponential moving average, so the estimate isn't noisy at the beginning.
+type sendersProgress struct {
+	from, to  uint64
+	prevBlock uint64
+	prevTime  time.Time
+	speed     float64 // blocks per second
+}
+
+func (p *sendersProgress) update(block uint64, now time.Time) (percent float64, eta time.Duration) {
+	const smoothing = 0.3
+	if block > p.prevBlock && now.After(p.prevTime) {
+		speed := float64(block-p.prevBlock) / now.Sub(p.prevTime).Seconds()
+		if p.speed == 0 {
+			p.speed = speed
+		} else {
+			p.speed = smoothing*speed + (1-smoothing)*p.speed
+		}
+		p.prevBlock, p.prevTime = block, now
+	}
+	if p.to > p.from {
+		percent = 100 * float64(block-p.from) / float64(p.to-p.from)
+	}
+	if p.speed > 0 && p.to > block {
+		eta = time.Duration(float64(p.to-block) / p.speed * float64(time.Second)).Round(time.Second)
+	}
+	return percent, eta
+}
+
+// throughputInterval - how often the recovery publishes its throughput
+const throughputInterval = 5 * time.Second
+
+// sendersThroughput measures how many blocks and transactions the recovery processes per second
+// and publishes it to the gauges and to stages.GetThroughput, which is served by the remote KV server.
+type sendersThroughput struct {
+	blocks, txs         uint64
+	prevBlocks, prevTxs uint64
+	prevTime            time.Time
+	blocksGauge         metrics.GaugeFloat64
+	txsGauge            metrics.GaugeFloat64
+}
+
+func newSendersThroughput(now time.Time) *sendersThroughput {
+	return &sendersThroughput{prevTime: now, blocksGauge: sendersBlocksRateGauge, txsGauge: sendersTxsRateGauge}
+}
+
+func (t *sendersThroughput) add(txs int) {
+	t.blocks++
+	t.txs += uint64(txs)
+}
+
+// publish reports the throughput since the previous call
+func (t *sendersThroughput) publish(now time.Time) {
+	elapsed := now.Sub(t.prevTime).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	t.set(stages.Throughput{
+		BlocksPerSecond: float64(t.blocks-t.prevBlocks) / elapsed,
+		TxsPerSecond:    float64(t.txs-t.prevTxs) / elapsed,
+		Updated:         now,
+	})
+	t.prevBlocks, t.prevTxs, t.prevTime = t.blocks, t.txs, now
+}
+
+// stop reports that the recovery doesn't process anything anymore
+func (t *sendersThroughput) stop(now time.Time) {
+	t.set(stages.Throughput{Updated: now})
+}
+
+func (t *sendersThroughput) set(snapshot stages.Throughput) {
+	t.blocksGauge.Update(snapshot.BlocksPerSecond)
+	t.txsGauge.Update(snapshot.TxsPerSecond)
+	stages.SetThroughput(stages.Senders, snapshot)
+}
+
+// createCryptoContext and destroyCryptoContext manage the contexts beyond the preallocated ones, tests replace them
+var (
+	createCryptoContext  = secp256k1.NewContext
+	destroyCryptoContext = (*secp256k1.Context).Destroy
+)
+
+// newCryptoContexts makes a crypto context for each of n recoverer goroutines, to make sure they are really parallel.
+// The contexts are built for every run, so the worker count can change between runs.
+// The returned function frees the contexts created here and must be called once the goroutines are done.
+func newCryptoContexts(n int) ([]*secp256k1.Context, func()) {
+	cryptoContexts := make([]*secp256k1.Context, n)
+	var created []*secp256k1.Context
+	for i := range cryptoContexts {
+		if i < secp256k1.NumOfContexts() {
+			cryptoContexts[i] = secp256k1.ContextForThread(i)
+		} else {
+			cryptoContexts[i] = createCryptoContext()
+			created = append(created, cryptoContexts[i])
+		}
+	}
+	return cryptoContexts, func() {
+		for _, c := range created {
+			destroyCryptoContext(c)
+		}
+	}
+}
+
 type senderRecoveryJob struct {
 	bodyRlp     rlp.RawValue
 	blockNumber uint64
 	index       int
 	senders     []byte
+	skipped     []*TxSenderError // transactions with invalid chain id, see Stage3Config.SkipInvalidChainID
 	err         error
 }
 
-func recoverSenders(cryptoContext *secp256k1.Context, config *params.ChainConfig, in, out chan *senderRecoveryJob, quit <-chan struct{}) {
+// TxSenderError - sender of a single transaction can't be recovered, it tells which transaction poisons the block
+type TxSenderError struct {
+	BlockNumber uint64
+	TxIndex     int
+	TxHash      common.Hash
+	Err         error
+}
+
+func (e *TxSenderError) Error() string {
+	return fmt.Sprintf("error recovering sender for tx=%x (index %d), %v", e.TxHash, e.TxIndex, e.Err)
+}
+
+func (e *TxSenderError) Unwrap() error { return e.Err }
+
+// signerCache reuses a signer until the next block at which types.MakeSigner would return a different one
+type signerCache struct {
+	config   *params.ChainConfig
+	forks    []uint64 // sorted heights of the forks changing the signer
+	signer   types.Signer
+	from, to uint64 // signer is valid for the blocks [from, to)
+}
+
+func newSignerCache(config *params.ChainConfig) *signerCache {
+	c := &signerCache{config: config}
+	for _, fork := range types.SignerForkBlocks(config) {
+		if fork.IsUint64() {
+			c.forks = append(c.forks, fork.Uint64())
+		}
+	}
+	sort.Slice(c.forks, func(i, j int) bool { return c.forks[i] < c.forks[j] })
+	return c
+}
+
+func (c *signerCache) get(blockNumber uint64) types.Signer {
+	if c.signer != nil && blockNumber >= c.from && blockNumber < c.to {
+		return c.signer
+	}
+	c.signer = types.MakeSigner(c.config, new(big.Int).SetUint64(blockNumber))
+	c.from, c.to = 0, math.MaxUint64
+	for _, fork := range c.forks {
+		if fork <= blockNumber {
+			c.from = fork
+		} else {
+			c.to = fork
+			break
+		}
+	}
+	return c.signer
+}
+
+// sendersBuffer returns a zeroed buffer of the given size, reusing a recycled one if it's big enough
+func sendersBuffer(free chan []byte, size int) []byte {
+	select {
+	case buf := <-free:
+		if cap(buf) >= size {
+			buf = buf[:size]
+			for i := range buf {
+				buf[i] = 0 // senders of skipped transactions stay zero
+			}
+			return buf
+		}
+	default:
+	}
+	return make([]byte, size)
+}
+
+// recycleSendersBuffer passes the buffer back to the recoverers, the buffer must not be used after that
+func recycleSendersBuffer(free chan []byte, buf []byte) {
+	if cap(buf) == 0 {
+		return
+	}
+	select {
+	case free <- buf:
+	default: // enough buffers are free already
+	}
+}
+
+// recoverSenders recovers senders of the jobs from in and sends them to out. Senders buffers are taken from free,
+// the consumer may put them back with recycleSendersBuffer once it doesn't need them.
+func recoverSenders(ctx context.Context, cryptoContext *secp256k1.Context, config *params.ChainConfig, skipInvalidChainID bool, in, out chan *senderRecoveryJob, free chan []byte) {
+	signers := newSignerCache(config)
 	for job := range in {
 		if job == nil {
 			return
@@ -225,39 +684,84 @@ func recoverSenders(cryptoContext *secp256k1.Context, config *params.ChainConfig
 		body := new(types.Body)
 		if err := rlp.Decode(bytes.NewReader(job.bodyRlp), body); err != nil {
 			job.err = fmt.Errorf("invalid block body RLP: %w", err)
-			out <- job
+			select {
+			case out <- job:
+			case <-ctx.Done():
+			}
 			return
 		}
-		signer := types.MakeSigner(config, big.NewInt(int64(job.blockNumber)))
-		job.senders = make([]byte, len(body.Transactions)*common.AddressLength)
-		for i, tx := range body.Transactions {
-			from, err := signer.SenderWithContext(cryptoContext, tx)
-			if err != nil {
-				job.err = fmt.Errorf("error recovering sender for tx=%x, %w", tx.Hash(), err)
-				break
-			}
-			if tx.Protected() && tx.ChainID().Cmp(signer.ChainID()) != 0 {
-				job.err = errors.New("invalid chainId")
-				break
+		if len(body.Transactions) == 0 {
+			// nothing to recover, skip making a signer and allocating senders
+			select {
+			case out <- job:
+			case <-ctx.Done():
+				return
 			}
-			copy(job.senders[i*common.AddressLength:], from[:])
+			continue
 		}
+		job.senders = sendersBuffer(free, len(body.Transactions)*common.AddressLength)
+		recoverBlock(cryptoContext, signers.get(job.blockNumber), skipInvalidChainID, job, body.Transactions)
 
-		// prevent sending to close channel
-		if err := common.Stopped(quit); err != nil {
-			job.err = err
+		select {
+		case out <- job:
+		case <-ctx.Done():
+			return
 		}
-		out <- job
+	}
+}
 
-		if errors.Is(job.err, common.ErrStopped) {
+// recoverBlock fills job.senders with the senders of the block transactions. A panic during the recovery
+// fails the job with the transaction that caused it, instead of crashing the node.
+func recoverBlock(cryptoContext *secp256k1.Context, signer types.Signer, skipInvalidChainID bool, job *senderRecoveryJob, txs types.Transactions) {
+	i := 0
+	defer func() {
+		if r := recover(); r != nil {
+			job.err = &TxSenderError{BlockNumber: job.blockNumber, TxIndex: i, TxHash: txs[i].Hash(), Err: fmt.Errorf("%w: %v", ErrSenderRecoveryPanic, r)}
+		}
+	}()
+	for ; i < len(txs); i++ {
+		from, err := recoverFrom(cryptoContext, signer, txs[i])
+		if err != nil {
+			txErr := &TxSenderError{BlockNumber: job.blockNumber, TxIndex: i, TxHash: txs[i].Hash(), Err: err}
+			if skipInvalidChainID && errors.Is(err, types.ErrInvalidChainId) {
+				// leave the zero address as the sender and let the consumer report the transaction
+				job.skipped = append(job.skipped, txErr)
+				continue
+			}
+			job.err = txErr
 			return
 		}
+		copy(job.senders[i*common.AddressLength:], from[:])
 	}
 }
 
+// recoverFrom recovers the sender of the transaction and checks that protected transactions are signed for this chain
+func recoverFrom(cryptoContext *secp256k1.Context, signer types.Signer, tx *types.Transaction) (common.Address, error) {
+	from, err := signer.SenderWithContext(cryptoContext, tx)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if tx.Protected() && tx.ChainID().Cmp(signer.ChainID()) != 0 {
+		return common.Address{}, types.ErrInvalidChainId
+	}
+	return from, nil
+}
+
 func UnwindSendersStage(u *UnwindState, stateDB ethdb.Database) error {
-	// Does not require any special processing
 	mutation := stateDB.NewBatch()
+	// senders of the unwound blocks must be recovered again, once their new canonical bodies are known
+	var keys [][]byte
+	if err := stateDB.Walk(dbutils.Senders, dbutils.EncodeBlockNumber(u.UnwindPoint+1), 0, func(k, _ []byte) (bool, error) {
+		keys = append(keys, common.CopyBytes(k))
+		return true, nil
+	}); err != nil {
+		return fmt.Errorf("unwind Senders: walking over senders: %w", err)
+	}
+	for _, k := range keys {
+		if err := mutation.Delete(dbutils.Senders, k); err != nil {
+			return fmt.Errorf("unwind Senders: deleting senders: %w", err)
+		}
+	}
 	err := u.Done(mutation)
 	if err != nil {
 		return fmt.Errorf("unwind Senders: reset: %v", err)
@@ -268,3 +772,30 @@ func UnwindSendersStage(u *UnwindState, stateDB ethdb.Database) error {
 	}
 	return nil
 }
+
+// ErrSendersCount - stored senders of the block don't match transactions of its body
+var ErrSendersCount = errors.New("senders don't match transactions of the block")
+
+// ErrZeroSender - the stored sender of a transaction is the zero address, which Stage3Config.SkipInvalidChainID
+// stores for transactions it couldn't recover
+var ErrZeroSender = errors.New("sender of the transaction is unknown, it was skipped by senders recovery")
+
+// checkZeroSenders returns ErrZeroSender for the first zero sender of the block
+func checkZeroSenders(number uint64, senders []common.Address) error {
+	for i := range senders {
+		if senders[i] == (common.Address{}) {
+			return fmt.Errorf("%w: block %d, tx %d", ErrZeroSender, number, i)
+		}
+	}
+	return nil
+}
+
+// applySenders sets stored senders to transactions of the body. Every transaction must have its sender,
+// so a genesis or other block without transactions must have no senders stored either.
+func applySenders(number uint64, body *types.Body, senders []common.Address) error {
+	if len(senders) != len(body.Transactions) {
+		return fmt.Errorf("%w: block %d has %d transactions and %d senders", ErrSendersCount, number, len(body.Transactions), len(senders))
+	}
+	body.SendersToTxs(senders)
+	return nil
+}
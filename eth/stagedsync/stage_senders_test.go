@@ -0,0 +1,1044 @@
+package stagedsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/core"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/crypto"
+	"github.com/ledgerwatch/turbo-geth/crypto/secp256k1"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/log"
+	"github.com/ledgerwatch/turbo-geth/metrics"
+	"github.com/ledgerwatch/turbo-geth/params"
+	"github.com/ledgerwatch/turbo-geth/rlp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSendersTestChain writes canonical hashes and bodies with signed transactions for blocks [1, blocks]
+// and returns the expected senders of every block, indexed by block number
+func writeSendersTestChain(t testing.TB, db ethdb.Database, config *params.ChainConfig, blocks uint64, txsPerBlock int) [][]common.Address {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	expect := make([][]common.Address, blocks+1)
+	var nonce uint64
+	for n := uint64(1); n <= blocks; n++ {
+		signer := types.MakeSigner(config, new(big.Int).SetUint64(n))
+		body := &types.Body{}
+		for i := 0; i < txsPerBlock; i++ {
+			tx, err := types.SignTx(types.NewTransaction(nonce, common.Address{1}, uint256.NewInt(), 21000, uint256.NewInt(), nil), signer, key)
+			require.NoError(t, err)
+			nonce++
+			body.Transactions = append(body.Transactions, tx)
+			expect[n] = append(expect[n], from)
+		}
+		hash := common.Hash{byte(n), byte(n >> 8), 1}
+		rawdb.WriteCanonicalHash(db, hash, n)
+		rawdb.WriteBody(context.Background(), db, hash, n, body)
+	}
+	require.NoError(t, stages.SaveStageProgress(db, stages.Bodies, blocks, nil))
+	return expect
+}
+
+func testSendersConfig() Stage3Config {
+	cfg := DefaultStage3Config()
+	cfg.BatchSize = 16
+	cfg.NumOfGoroutines = runtime.NumCPU()
+	return cfg
+}
+
+func TestSendersStageRunsToCompletion(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+
+	expect := writeSendersTestChain(t, db, config, 20, 3)
+
+	err := SpawnRecoverSendersStage(context.Background(), testSendersConfig(), &StageState{Stage: stages.Senders}, db, config, 0, "")
+	require.NoError(t, err)
+
+	progress, _, err := stages.GetStageProgress(db, stages.Senders)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(20), progress)
+	for n := uint64(1); n <= 20; n++ {
+		hash := common.Hash{byte(n), byte(n >> 8), 1}
+		assert.Equal(t, expect[n], rawdb.ReadSenders(db, hash, n), "block %d", n)
+	}
+}
+
+func TestSendersStageCancelled(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+
+	writeSendersTestChain(t, db, config, 20, 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := SpawnRecoverSendersStage(ctx, testSendersConfig(), &StageState{Stage: stages.Senders}, db, config, 0, "")
+	assert.True(t, errors.Is(err, context.Canceled), "unexpected error %v", err)
+
+	progress, _, err := stages.GetStageProgress(db, stages.Senders)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), progress)
+}
+
+func TestSendersProgress(t *testing.T) {
+	start := time.Now()
+	p := &sendersProgress{from: 100, to: 1100, prevBlock: 100, prevTime: start}
+
+	percent, eta := p.update(200, start.Add(10*time.Second))
+	assert.Equal(t, 10.0, percent)
+	assert.Equal(t, 90*time.Second, eta)
+
+	// speed drops from 10 to 5 blocks per second, the estimate follows it only partially
+	percent, eta = p.update(250, start.Add(20*time.Second))
+	assert.Equal(t, 15.0, percent)
+	assert.Equal(t, 8.5, p.speed)
+	assert.Equal(t, 100*time.Second, eta)
+}
+
+func TestSendersThroughput(t *testing.T) {
+	defer stages.SetThroughput(stages.Senders, stages.Throughput{})
+	start := time.Now()
+	tp := newSendersThroughput(start)
+	tp.blocksGauge, tp.txsGauge = &metrics.StandardGaugeFloat64{}, &metrics.StandardGaugeFloat64{}
+
+	for i := 0; i < 20; i++ {
+		tp.add(3)
+	}
+	tp.publish(start.Add(4 * time.Second))
+	assert.Equal(t, 5.0, tp.blocksGauge.Value())
+	assert.Equal(t, 15.0, tp.txsGauge.Value())
+	assert.Equal(t, stages.Throughput{BlocksPerSecond: 5, TxsPerSecond: 15, Updated: start.Add(4 * time.Second)}, stages.GetThroughput(stages.Senders))
+
+	// only blocks processed since the previous snapshot count
+	for i := 0; i < 10; i++ {
+		tp.add(0)
+	}
+	tp.publish(start.Add(6 * time.Second))
+	assert.Equal(t, 5.0, tp.blocksGauge.Value())
+	assert.Equal(t, 0.0, tp.txsGauge.Value())
+
+	tp.stop(start.Add(7 * time.Second))
+	assert.Equal(t, 0.0, tp.blocksGauge.Value())
+	assert.Equal(t, stages.Throughput{Updated: start.Add(7 * time.Second)}, stages.GetThroughput(stages.Senders))
+}
+
+func TestSendersStageThroughputStopped(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+	defer stages.SetThroughput(stages.Senders, stages.Throughput{})
+
+	writeSendersTestChain(t, db, config, 20, 3)
+	stages.SetThroughput(stages.Senders, stages.Throughput{BlocksPerSecond: 1000})
+	require.NoError(t, SpawnRecoverSendersStage(context.Background(), testSendersConfig(), &StageState{Stage: stages.Senders}, db, config, 0, ""))
+
+	// a finished stage doesn't look like it's still recovering
+	throughput := stages.GetThroughput(stages.Senders)
+	assert.Equal(t, 0.0, throughput.BlocksPerSecond)
+	assert.False(t, throughput.Updated.IsZero())
+}
+
+func BenchmarkSendersStageEmptyBlocks(b *testing.B) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+
+	writeSendersTestChain(b, db, config, 10000, 0)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := SpawnRecoverSendersStage(context.Background(), testSendersConfig(), &StageState{Stage: stages.Senders}, db, config, 0, ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestSignerCache(t *testing.T) {
+	for _, config := range []*params.ChainConfig{params.MainnetChainConfig, params.RopstenChainConfig, params.AllEthashProtocolChanges} {
+		var heights []uint64
+		for _, fork := range types.SignerForkBlocks(config) {
+			f := fork.Uint64()
+			heights = append(heights, f, f+1, f+1000)
+			if f > 0 {
+				heights = append(heights, f-1, f/2)
+			}
+		}
+		cache := newSignerCache(config)
+		// walk the heights forward, backward and forward again to cross every boundary in both directions
+		for _, order := range [][]uint64{heights, reversed(heights), heights} {
+			for _, n := range order {
+				expected := types.MakeSigner(config, new(big.Int).SetUint64(n))
+				signer := cache.get(n)
+				assert.IsType(t, expected, signer, "block %d", n)
+				assert.True(t, expected.Equal(signer), "block %d", n)
+			}
+		}
+	}
+}
+
+func reversed(a []uint64) []uint64 {
+	r := make([]uint64, len(a))
+	for i := range a {
+		r[len(a)-1-i] = a[i]
+	}
+	return r
+}
+
+func TestUnwindSendersStage(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+
+	expect := writeSendersTestChain(t, db, config, 20, 2)
+	require.NoError(t, SpawnRecoverSendersStage(context.Background(), testSendersConfig(), &StageState{Stage: stages.Senders}, db, config, 0, ""))
+
+	require.NoError(t, UnwindSendersStage(&UnwindState{Stage: stages.Senders, UnwindPoint: 15}, db))
+	progress, _, err := stages.GetStageProgress(db, stages.Senders)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(15), progress)
+	for n := uint64(1); n <= 20; n++ {
+		hash := common.Hash{byte(n), byte(n >> 8), 1}
+		if n <= 15 {
+			assert.Equal(t, expect[n], rawdb.ReadSenders(db, hash, n), "block %d", n)
+		} else {
+			assert.Empty(t, rawdb.ReadSenders(db, hash, n), "block %d", n)
+		}
+	}
+
+	// mark a block below the unwind point, it must not be recovered again
+	tampered := []common.Address{{0xff}, {0xff}}
+	rawdb.WriteSenders(context.Background(), db, common.Hash{10, 0, 1}, 10, tampered)
+
+	require.NoError(t, SpawnRecoverSendersStage(context.Background(), testSendersConfig(), &StageState{Stage: stages.Senders, BlockNumber: progress}, db, config, 0, ""))
+	assert.Equal(t, tampered, rawdb.ReadSenders(db, common.Hash{10, 0, 1}, 10))
+	for n := uint64(16); n <= 20; n++ {
+		hash := common.Hash{byte(n), byte(n >> 8), 1}
+		assert.Equal(t, expect[n], rawdb.ReadSenders(db, hash, n), "block %d", n)
+	}
+}
+
+func TestUnwindSendersStageByOneBlock(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+
+	expect := writeSendersTestChain(t, db, config, 20, 1)
+	require.NoError(t, SpawnRecoverSendersStage(context.Background(), testSendersConfig(), &StageState{Stage: stages.Senders}, db, config, 0, ""))
+
+	require.NoError(t, UnwindSendersStage(&UnwindState{Stage: stages.Senders, UnwindPoint: 19}, db))
+	progress, _, err := stages.GetStageProgress(db, stages.Senders)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(19), progress)
+	unwind, _, err := stages.GetStageUnwind(db, stages.Senders)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), unwind)
+
+	// wipe the remaining senders, only block 20 is expected to be recovered again
+	for n := uint64(1); n <= 19; n++ {
+		rawdb.WriteSenders(context.Background(), db, common.Hash{byte(n), byte(n >> 8), 1}, n, nil)
+	}
+	require.NoError(t, SpawnRecoverSendersStage(context.Background(), testSendersConfig(), &StageState{Stage: stages.Senders, BlockNumber: progress}, db, config, 0, ""))
+	for n := uint64(1); n <= 19; n++ {
+		assert.Empty(t, rawdb.ReadSenders(db, common.Hash{byte(n), byte(n >> 8), 1}, n), "block %d", n)
+	}
+	assert.Equal(t, expect[20], rawdb.ReadSenders(db, common.Hash{20, 0, 1}, 20))
+}
+
+func TestSendersStageInvalidChainID(t *testing.T) {
+	config := params.AllEthashProtocolChanges
+	for _, skip := range []bool{false, true} {
+		db := ethdb.NewMemDatabase()
+		expect := writeSendersTestChain(t, db, config, 5, 3)
+
+		// replace the middle transaction of block 3 by one signed for another chain
+		key, err := crypto.GenerateKey()
+		require.NoError(t, err)
+		hash := common.Hash{3, 0, 1}
+		body := rawdb.ReadBody(db, hash, 3)
+		body.Transactions[1], err = types.SignTx(types.NewTransaction(0, common.Address{1}, uint256.NewInt(), 21000, uint256.NewInt(), nil), types.NewEIP155Signer(big.NewInt(12345)), key)
+		require.NoError(t, err)
+		rawdb.WriteBody(context.Background(), db, hash, 3, body)
+
+		cfg := testSendersConfig()
+		cfg.SkipInvalidChainID = skip
+		err = SpawnRecoverSendersStage(context.Background(), cfg, &StageState{Stage: stages.Senders}, db, config, 0, "")
+		if !skip {
+			assert.True(t, errors.Is(err, types.ErrInvalidChainId), "unexpected error %v", err)
+			db.Close()
+			continue
+		}
+		require.NoError(t, err)
+		expect[3][1] = common.Address{}
+		for n := uint64(1); n <= 5; n++ {
+			assert.Equal(t, expect[n], rawdb.ReadSenders(db, common.Hash{byte(n), byte(n >> 8), 1}, n), "block %d", n)
+		}
+		db.Close()
+	}
+}
+
+func TestSendersStageVerify(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+
+	expect := writeSendersTestChain(t, db, config, 10, 3)
+	require.NoError(t, SpawnRecoverSendersStage(context.Background(), testSendersConfig(), &StageState{Stage: stages.Senders}, db, config, 0, ""))
+	cfg := testSendersConfig()
+	cfg.Verify = true
+	require.NoError(t, SpawnRecoverSendersStage(context.Background(), cfg, &StageState{Stage: stages.Senders}, db, config, 0, ""))
+
+	// corrupt one stored sender
+	hash := common.Hash{4, 0, 1}
+	var corrupted []byte
+	for _, from := range expect[4] {
+		corrupted = append(corrupted, from.Bytes()...)
+	}
+	corrupted[common.AddressLength+5] ^= 0xff // second transaction
+	require.NoError(t, db.Put(dbutils.Senders, dbutils.BlockBodyKey(4, hash), corrupted))
+
+	err := SpawnRecoverSendersStage(context.Background(), cfg, &StageState{Stage: stages.Senders}, db, config, 0, "")
+	var mismatchErr *SendersMismatchError
+	require.True(t, errors.As(err, &mismatchErr), "unexpected error %v", err)
+	assert.Equal(t, 1, mismatchErr.Mismatches)
+
+	// nothing is written
+	stored, err := db.Get(dbutils.Senders, dbutils.BlockBodyKey(4, hash))
+	require.NoError(t, err)
+	assert.Equal(t, corrupted, stored)
+	progress, _, err := stages.GetStageProgress(db, stages.Senders)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), progress)
+
+	// pruned senders aren't reported as mismatches
+	require.NoError(t, rawdb.PruneSenders(db, 5))
+	require.NoError(t, SpawnRecoverSendersStage(context.Background(), cfg, &StageState{Stage: stages.Senders}, db, config, 0, ""))
+}
+
+func TestSendersStageLogger(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+	writeSendersTestChain(t, db, config, 5, 1)
+
+	var records []*log.Record
+	logger := log.New("run", 42)
+	logger.SetHandler(log.FuncHandler(func(r *log.Record) error {
+		records = append(records, r) // only the stage goroutine logs in this run
+		return nil
+	}))
+	cfg := testSendersConfig()
+	cfg.Logger = logger
+	require.NoError(t, SpawnRecoverSendersStage(context.Background(), cfg, &StageState{Stage: stages.Senders}, db, config, 0, ""))
+
+	require.NotEmpty(t, records)
+	for _, r := range records {
+		assert.Equal(t, []interface{}{"run", 42, "stage", "senders"}, r.Ctx[:4], r.Msg)
+	}
+}
+
+func TestSendersStageDifferentWorkerCounts(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+
+	expect := writeSendersTestChain(t, db, config, 20, 2)
+	for _, workers := range []int{secp256k1.NumOfContexts() + 2, 1} {
+		cfg := testSendersConfig()
+		cfg.NumOfGoroutines = workers
+		require.NoError(t, SpawnRecoverSendersStage(context.Background(), cfg, &StageState{Stage: stages.Senders}, db, config, 0, ""))
+		for n := uint64(1); n <= 20; n++ {
+			assert.Equal(t, expect[n], rawdb.ReadSenders(db, common.Hash{byte(n), byte(n >> 8), 1}, n), "block %d", n)
+		}
+		require.NoError(t, UnwindSendersStage(&UnwindState{Stage: stages.Senders}, db))
+	}
+}
+
+func TestSendersStageReleasesCryptoContexts(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+
+	writeSendersTestChain(t, db, config, 5, 1)
+	contexts := trackCryptoContexts(t)
+	for i := 0; i < 10; i++ {
+		cfg := testSendersConfig()
+		cfg.NumOfGoroutines = secp256k1.NumOfContexts() + 2
+		require.NoError(t, SpawnRecoverSendersStage(context.Background(), cfg, &StageState{Stage: stages.Senders}, db, config, 0, ""))
+		created, live := contexts.counts()
+		assert.Equal(t, 2*(i+1), created)
+		assert.Zero(t, live, "run %d left contexts not destroyed", i)
+	}
+}
+
+// cryptoContextTracker records the contexts made by newCryptoContexts which aren't destroyed yet
+type cryptoContextTracker struct {
+	lock    sync.Mutex
+	created int
+	live    map[*secp256k1.Context]struct{}
+}
+
+func (c *cryptoContextTracker) counts() (created, live int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.created, len(c.live)
+}
+
+func trackCryptoContexts(t *testing.T) *cryptoContextTracker {
+	tracker := &cryptoContextTracker{live: map[*secp256k1.Context]struct{}{}}
+	create, destroy := createCryptoContext, destroyCryptoContext
+	createCryptoContext = func() *secp256k1.Context {
+		c := create()
+		tracker.lock.Lock()
+		defer tracker.lock.Unlock()
+		tracker.created++
+		tracker.live[c] = struct{}{}
+		return c
+	}
+	destroyCryptoContext = func(c *secp256k1.Context) {
+		tracker.lock.Lock()
+		_, ok := tracker.live[c]
+		delete(tracker.live, c)
+		tracker.lock.Unlock()
+		assert.True(t, ok, "context destroyed twice or not created by newCryptoContexts")
+		destroy(c)
+	}
+	t.Cleanup(func() {
+		createCryptoContext, destroyCryptoContext = create, destroy
+	})
+	return tracker
+}
+
+func TestNewCryptoContextsDistinct(t *testing.T) {
+	for _, n := range []int{1, secp256k1.NumOfContexts(), secp256k1.NumOfContexts() + 3} {
+		contexts, release := newCryptoContexts(n)
+		require.Len(t, contexts, n)
+		// ContextForThread makes a new wrapper on every call, so compare the wrapped secp256k1 contexts
+		seen := make(map[secp256k1.Context]int, n)
+		for threadNo, c := range contexts {
+			require.NotNil(t, c)
+			prev, ok := seen[*c]
+			require.False(t, ok, "workers %d and %d share a crypto context, n=%d", prev, threadNo, n)
+			seen[*c] = threadNo
+		}
+		release()
+	}
+}
+
+func TestApplySendersGenesis(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+
+	genesis := core.DefaultGenesisBlock().MustCommit(db)
+	body := rawdb.ReadBody(db, genesis.Hash(), 0)
+	require.NotNil(t, body)
+	require.NoError(t, applySenders(0, body, rawdb.ReadSenders(db, genesis.Hash(), 0)))
+	require.NoError(t, applySenders(0, body, nil))
+	err := applySenders(0, body, []common.Address{{1}})
+	require.True(t, errors.Is(err, ErrSendersCount), "unexpected error %v", err)
+}
+
+func TestApplySendersEmptyBodies(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+
+	writeSendersTestChain(t, db, config, 5, 0)
+	require.NoError(t, SpawnRecoverSendersStage(context.Background(), testSendersConfig(), &StageState{Stage: stages.Senders}, db, config, 0, ""))
+	for n := uint64(1); n <= 5; n++ {
+		hash := common.Hash{byte(n), byte(n >> 8), 1}
+		body := rawdb.ReadBody(db, hash, n)
+		require.NotNil(t, body)
+		require.Empty(t, body.Transactions)
+		require.NoError(t, applySenders(n, body, rawdb.ReadSenders(db, hash, n)), "block %d", n)
+	}
+}
+
+func TestApplySendersMismatch(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+
+	expect := writeSendersTestChain(t, db, config, 1, 3)
+	hash := common.Hash{1, 0, 1}
+	body := rawdb.ReadBody(db, hash, 1)
+	require.NotNil(t, body)
+
+	// senders of the block were never written: no panic, the block is reported
+	err := applySenders(1, body, rawdb.ReadSenders(db, hash, 1))
+	require.True(t, errors.Is(err, ErrSendersCount), "unexpected error %v", err)
+	err = applySenders(1, body, append(expect[1], common.Address{2}))
+	require.True(t, errors.Is(err, ErrSendersCount), "unexpected error %v", err)
+
+	require.NoError(t, applySenders(1, body, expect[1]))
+	assert.Equal(t, expect[1], body.SendersFromTxs())
+}
+
+func BenchmarkRecoverFrom(b *testing.B) {
+	const txsPerBlock = 200
+	config := params.MainnetChainConfig
+	signer := types.MakeSigner(config, config.EIP155Block)
+	key, err := crypto.GenerateKey()
+	require.NoError(b, err)
+	txs := make([]*types.Transaction, txsPerBlock)
+	for i := range txs {
+		txs[i], err = types.SignTx(types.NewTransaction(uint64(i), common.Address{1}, uint256.NewInt(), 21000, uint256.NewInt(), make([]byte, 100)), signer, key)
+		require.NoError(b, err)
+	}
+	cryptoContext := secp256k1.NewContext()
+	defer cryptoContext.Destroy()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		for _, tx := range txs {
+			if _, err := recoverFrom(cryptoContext, signer, tx); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(time.Since(start).Nanoseconds())/float64(b.N*len(txs)), "ns/tx")
+}
+
+func TestSendersStageRecoveryError(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+
+	writeSendersTestChain(t, db, config, 200, 2)
+
+	// block 7 gets a transaction whose signature can't be recovered
+	hash := common.Hash{7, 0, 1}
+	body := rawdb.ReadBody(db, hash, 7)
+	invalid, err := types.NewTransaction(0, common.Address{1}, uint256.NewInt(), 21000, uint256.NewInt(), nil).WithSignature(types.HomesteadSigner{}, make([]byte, 65))
+	require.NoError(t, err)
+	body.Transactions[0] = invalid
+	rawdb.WriteBody(context.Background(), db, hash, 7, body)
+
+	contexts := trackCryptoContexts(t)
+	cfg := testSendersConfig()
+	cfg.BatchSize = 1
+	cfg.NumOfGoroutines = secp256k1.NumOfContexts() + 1
+	err = SpawnRecoverSendersStage(context.Background(), cfg, &StageState{Stage: stages.Senders}, db, config, 0, "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), invalid.Hash().Hex()[2:])
+
+	// the pipeline is cancelled, so all the recoverers exit and release their contexts
+	assert.Eventually(t, func() bool {
+		created, live := contexts.counts()
+		return created == 1 && live == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	progress, _, err := stages.GetStageProgress(db, stages.Senders)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), progress)
+}
+
+func TestSendersStageTxSenderError(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+
+	writeSendersTestChain(t, db, config, 10, 3)
+
+	// second transaction of block 7 has a signature which can't be recovered
+	hash := common.Hash{7, 0, 1}
+	body := rawdb.ReadBody(db, hash, 7)
+	invalid, err := types.NewTransaction(0, common.Address{1}, uint256.NewInt(), 21000, uint256.NewInt(), nil).WithSignature(types.HomesteadSigner{}, make([]byte, 65))
+	require.NoError(t, err)
+	body.Transactions[1] = invalid
+	rawdb.WriteBody(context.Background(), db, hash, 7, body)
+
+	err = SpawnRecoverSendersStage(context.Background(), testSendersConfig(), &StageState{Stage: stages.Senders}, db, config, 0, "")
+	var txErr *TxSenderError
+	require.True(t, errors.As(err, &txErr), "unexpected error %v", err)
+	assert.Equal(t, uint64(7), txErr.BlockNumber)
+	assert.Equal(t, 1, txErr.TxIndex)
+	assert.Equal(t, invalid.Hash(), txErr.TxHash)
+	assert.Error(t, txErr.Err)
+
+	// in lenient mode only the transaction with invalid chain id is skipped, with the same details
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	otherChain, err := types.SignTx(types.NewTransaction(0, common.Address{1}, uint256.NewInt(), 21000, uint256.NewInt(), nil), types.NewEIP155Signer(big.NewInt(12345)), key)
+	require.NoError(t, err)
+	body.Transactions[1] = otherChain
+	bodyRlp, err := rlp.EncodeToBytes(body)
+	require.NoError(t, err)
+
+	in, out := make(chan *senderRecoveryJob, 1), make(chan *senderRecoveryJob, 1)
+	in <- &senderRecoveryJob{bodyRlp: bodyRlp, blockNumber: 7}
+	close(in)
+	cryptoContext := secp256k1.NewContext()
+	defer cryptoContext.Destroy()
+	// a recycled buffer still holds senders of another block
+	free := make(chan []byte, 1)
+	free <- bytes.Repeat([]byte{0xff}, 10*common.AddressLength)
+	recoverSenders(context.Background(), cryptoContext, config, true, in, out, free)
+	job := <-out
+	require.NoError(t, job.err)
+	require.Len(t, job.skipped, 1)
+	assert.Equal(t, &TxSenderError{BlockNumber: 7, TxIndex: 1, TxHash: otherChain.Hash(), Err: types.ErrInvalidChainId}, job.skipped[0])
+	assert.Equal(t, common.Address{}, common.BytesToAddress(job.senders[common.AddressLength:2*common.AddressLength]))
+	assert.NotEqual(t, common.Address{}, common.BytesToAddress(job.senders[:common.AddressLength]))
+}
+
+func TestSendersStageWrapsRecoveryError(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+
+	writeSendersTestChain(t, db, config, 5, 1)
+	rawdb.WriteBodyRLP(context.Background(), db, common.Hash{4, 0, 1}, 4, []byte{0xc1, 0xc0, 0xc0})
+
+	err := SpawnRecoverSendersStage(context.Background(), testSendersConfig(), &StageState{Stage: stages.Senders}, db, config, 0, "")
+	require.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "sync Senders: block 4: invalid block body RLP"), "unexpected error %v", err)
+	assert.NotNil(t, errors.Unwrap(errors.Unwrap(err)))
+}
+
+func TestPruneSenders(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+
+	expect := writeSendersTestChain(t, db, config, 20, 3)
+	require.NoError(t, SpawnRecoverSendersStage(context.Background(), testSendersConfig(), &StageState{Stage: stages.Senders}, db, config, 0, ""))
+
+	require.NoError(t, rawdb.PruneSenders(db, 11))
+	availableFrom, err := rawdb.SendersAvailableFrom(db)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(11), availableFrom)
+
+	// pruning below the watermark is a no-op
+	require.NoError(t, rawdb.PruneSenders(db, 5))
+	availableFrom, err = rawdb.SendersAvailableFrom(db)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(11), availableFrom)
+
+	for n := uint64(1); n <= 20; n++ {
+		hash := common.Hash{byte(n), byte(n >> 8), 1}
+		if n < 11 {
+			assert.Empty(t, rawdb.ReadSenders(db, hash, n), "block %d", n)
+		} else {
+			assert.Equal(t, expect[n], rawdb.ReadSenders(db, hash, n), "block %d", n)
+		}
+		senders, err := rawdb.ReadSendersOrRecover(db, config, hash, n)
+		require.NoError(t, err)
+		assert.Equal(t, expect[n], senders, "block %d", n)
+	}
+}
+
+func TestPruneSendersInterrupted(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+
+	expect := writeSendersTestChain(t, db, config, 20, 3)
+	require.NoError(t, SpawnRecoverSendersStage(context.Background(), testSendersConfig(), &StageState{Stage: stages.Senders}, db, config, 0, ""))
+
+	// pruning was interrupted after the watermark was saved and some senders below it were deleted
+	require.NoError(t, db.Put(dbutils.DatabaseInfoBucket, dbutils.SendersAvailableFromKey, dbutils.EncodeBlockNumber(11)))
+	for n := uint64(1); n <= 5; n++ {
+		require.NoError(t, db.Delete(dbutils.Senders, dbutils.BlockBodyKey(n, common.Hash{byte(n), byte(n >> 8), 1})))
+	}
+	for n := uint64(1); n < 11; n++ {
+		senders, err := rawdb.ReadSendersOrRecover(db, config, common.Hash{byte(n), byte(n >> 8), 1}, n)
+		require.NoError(t, err)
+		assert.Equal(t, expect[n], senders, "block %d", n)
+	}
+
+	// pruning to the same watermark finishes the job
+	require.NoError(t, rawdb.PruneSenders(db, 11))
+	for n := uint64(1); n <= 20; n++ {
+		hash := common.Hash{byte(n), byte(n >> 8), 1}
+		if n < 11 {
+			assert.Empty(t, rawdb.ReadSenders(db, hash, n), "block %d", n)
+		} else {
+			assert.Equal(t, expect[n], rawdb.ReadSenders(db, hash, n), "block %d", n)
+		}
+	}
+}
+
+func TestHeapCeiling(t *testing.T) {
+	heap := uint64(200)
+	queued := 3
+	c := &heapCeiling{limit: 100, heapAlloc: func() uint64 {
+		// the recoverers drain the queue and the heap drops with it
+		queued--
+		if queued == 0 {
+			heap = 50
+		}
+		return heap
+	}}
+	require.NoError(t, c.wait(context.Background(), func() int { return queued }))
+	assert.Equal(t, 1, c.pauses)
+	assert.Equal(t, 0, queued)
+
+	// the heap stays above the limit, the reader resumes once nothing is queued
+	heap, queued = 500, 2
+	c.heapAlloc = func() uint64 { queued--; return heap }
+	require.NoError(t, c.wait(context.Background(), func() int { return queued }))
+	assert.Equal(t, 2, c.pauses)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	queued = 10
+	c.heapAlloc = func() uint64 { return heap }
+	assert.True(t, errors.Is(c.wait(ctx, func() int { return queued }), context.Canceled))
+
+	// no limit, no pause
+	c = &heapCeiling{heapAlloc: func() uint64 { return math.MaxUint64 }}
+	require.NoError(t, c.wait(context.Background(), func() int { return queued }))
+	assert.Equal(t, 0, c.pauses)
+}
+
+func TestSendersStageHeapCeiling(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+	expect := writeSendersTestChain(t, db, config, 3*heapCheckEvery, 1)
+
+	var records []*log.Record
+	logger := log.New()
+	logger.SetHandler(log.FuncHandler(func(r *log.Record) error {
+		records = append(records, r) // only the stage goroutine logs in this run
+		return nil
+	}))
+	cfg := testSendersConfig()
+	cfg.Logger = logger
+	cfg.MaxHeapAlloc = 1 // always exceeded, the reader has to wait for the recoverers at every check
+	require.NoError(t, SpawnRecoverSendersStage(context.Background(), cfg, &StageState{Stage: stages.Senders}, db, config, 0, ""))
+
+	paused := false
+	for _, r := range records {
+		if strings.Contains(r.Msg, "heap ceiling") {
+			paused = true
+		}
+	}
+	assert.True(t, paused, "body reader wasn't throttled")
+	for n := uint64(1); n <= 3*heapCheckEvery; n++ {
+		hash := common.Hash{byte(n), byte(n >> 8), 1}
+		assert.Equal(t, expect[n], rawdb.ReadSenders(db, hash, n), "block %d", n)
+	}
+}
+
+func TestSendersStageBodyGap(t *testing.T) {
+	for _, missing := range []uint64{7, 20} {
+		db := ethdb.NewMemDatabase()
+		config := params.AllEthashProtocolChanges
+		writeSendersTestChain(t, db, config, 20, 2)
+		rawdb.DeleteBody(db, common.Hash{byte(missing), 0, 1}, missing)
+
+		err := SpawnRecoverSendersStage(context.Background(), testSendersConfig(), &StageState{Stage: stages.Senders}, db, config, 0, "")
+		assert.True(t, errors.Is(err, ErrMissingBody), "block %d: unexpected error %v", missing, err)
+		assert.Contains(t, err.Error(), fmt.Sprintf("block %d,", missing))
+
+		progress, _, err := stages.GetStageProgress(db, stages.Senders)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0), progress, "block %d", missing)
+		db.Close()
+	}
+}
+
+// BenchmarkSendersStageLockOSThread compares recovery with and without recoverers locked to OS threads,
+// the difference shows up on multi-socket machines, run with -cpu set to the number of cores
+func BenchmarkSendersStageLockOSThread(b *testing.B) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+
+	writeSendersTestChain(b, db, config, 2000, 10)
+
+	for _, lock := range []bool{false, true} {
+		b.Run(fmt.Sprintf("lock=%t", lock), func(b *testing.B) {
+			cfg := testSendersConfig()
+			cfg.BatchSize = 1000
+			cfg.LockOSThread = lock
+			for i := 0; i < b.N; i++ {
+				if err := SpawnRecoverSendersStage(context.Background(), cfg, &StageState{Stage: stages.Senders}, db, config, 0, ""); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestSendersStageAtTip(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+	writeSendersTestChain(t, db, config, 5, 1)
+	require.NoError(t, stages.SaveStageProgress(db, stages.Senders, 5, nil))
+
+	// trace and profile files are created in the working directory, the collector uses datadir
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd) //nolint:errcheck
+	datadir := filepath.Join(dir, "datadir")
+	require.NoError(t, os.Mkdir(datadir, 0755))
+
+	cfg := testSendersConfig()
+	cfg.StartTrace = true
+	cfg.Prof = true
+	require.NoError(t, SpawnRecoverSendersStage(context.Background(), cfg, &StageState{Stage: stages.Senders, BlockNumber: 5}, db, config, 0, datadir))
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1, "stage at tip must not create files")
+	files, err = ioutil.ReadDir(datadir)
+	require.NoError(t, err)
+	require.Empty(t, files, "stage at tip must not create files")
+	progress, _, err := stages.GetStageProgress(db, stages.Senders)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), progress)
+}
+
+// BenchmarkRecoverSenders - allocations of a recoverer per block, with senders buffers recycled by the consumer
+// as the stage does, and dropped
+func BenchmarkRecoverSenders(b *testing.B) {
+	config := params.MainnetChainConfig
+	signer := types.MakeSigner(config, config.EIP155Block)
+	key, err := crypto.GenerateKey()
+	require.NoError(b, err)
+	body := &types.Body{}
+	for i := 0; i < 200; i++ {
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), common.Address{1}, uint256.NewInt(), 21000, uint256.NewInt(), nil), signer, key)
+		require.NoError(b, err)
+		body.Transactions = append(body.Transactions, tx)
+	}
+	bodyRlp, err := rlp.EncodeToBytes(body)
+	require.NoError(b, err)
+	cryptoContext := secp256k1.NewContext()
+	defer cryptoContext.Destroy()
+
+	for _, recycle := range []bool{false, true} {
+		b.Run(fmt.Sprintf("recycle=%t", recycle), func(b *testing.B) {
+			in, out, free := make(chan *senderRecoveryJob, 1), make(chan *senderRecoveryJob, 1), make(chan []byte, 1)
+			go recoverSenders(context.Background(), cryptoContext, config, false, in, out, free)
+			defer close(in)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				in <- &senderRecoveryJob{bodyRlp: bodyRlp, blockNumber: config.EIP155Block.Uint64()}
+				j := <-out
+				if recycle {
+					recycleSendersBuffer(free, j.senders)
+				}
+			}
+		})
+	}
+}
+
+// panickingSigner - fails like a signer hitting a bug on a malformed transaction
+type panickingSigner struct {
+	types.Signer
+	panicOn common.Hash
+}
+
+func (s panickingSigner) SenderWithContext(cryptoContext *secp256k1.Context, tx *types.Transaction) (common.Address, error) {
+	if tx.Hash() == s.panicOn {
+		panic("malformed signature")
+	}
+	return s.Signer.SenderWithContext(cryptoContext, tx)
+}
+
+func TestRecoverBlockPanic(t *testing.T) {
+	config := params.AllEthashProtocolChanges
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	writeSendersTestChain(t, db, config, 1, 3)
+	body := rawdb.ReadBody(db, common.Hash{1, 0, 1}, 1)
+	require.NotNil(t, body)
+	bad := body.Transactions[1]
+
+	cryptoContext := secp256k1.NewContext()
+	defer cryptoContext.Destroy()
+	job := &senderRecoveryJob{blockNumber: 1, senders: make([]byte, 3*common.AddressLength)}
+	signer := panickingSigner{Signer: types.MakeSigner(config, big.NewInt(1)), panicOn: bad.Hash()}
+	require.NotPanics(t, func() { recoverBlock(cryptoContext, signer, false, job, body.Transactions) })
+
+	var txErr *TxSenderError
+	require.True(t, errors.As(job.err, &txErr), "unexpected error %v", job.err)
+	assert.True(t, errors.Is(job.err, ErrSenderRecoveryPanic))
+	assert.Equal(t, uint64(1), txErr.BlockNumber)
+	assert.Equal(t, 1, txErr.TxIndex)
+	assert.Equal(t, bad.Hash(), txErr.TxHash)
+	assert.Contains(t, job.err.Error(), "malformed signature")
+}
+
+func TestSendersStageMaxTempFilesSize(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+	expect := writeSendersTestChain(t, db, config, 50, 3)
+
+	var records []*log.Record
+	logger := log.New()
+	logger.SetHandler(log.FuncHandler(func(r *log.Record) error {
+		records = append(records, r) // only the stage goroutine logs in this run
+		return nil
+	}))
+	datadir := t.TempDir()
+	cfg := testSendersConfig()
+	cfg.Logger = logger
+	cfg.BufferSize = 100 // senders of one block, flushed to a file every other block
+	cfg.MaxTempFilesSize = 200
+	require.NoError(t, SpawnRecoverSendersStage(context.Background(), cfg, &StageState{Stage: stages.Senders}, db, config, 0, datadir))
+
+	loads := 0
+	for _, r := range records {
+		if strings.Contains(r.Msg, "temporary files reached the limit") {
+			loads++
+		}
+	}
+	assert.GreaterOrEqual(t, loads, 10)
+	for n := uint64(1); n <= 50; n++ {
+		hash := common.Hash{byte(n), byte(n >> 8), 1}
+		assert.Equal(t, expect[n], rawdb.ReadSenders(db, hash, n), "block %d", n)
+	}
+	files, err := ioutil.ReadDir(filepath.Join(datadir, "etl-temp")) // the directory is shared by etl runs and stays
+	require.NoError(t, err)
+	assert.Empty(t, files, "temporary files must be removed")
+	progress, _, err := stages.GetStageProgress(db, stages.Senders)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(50), progress)
+}
+
+func TestSendersStageMaxTempFilesSizeWithinTx(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+	expect := writeSendersTestChain(t, db, config, 20, 3)
+
+	tx, err := ethdb.NewTxDbWithoutTransaction(db).Begin(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback()
+	var ignored, loads int
+	logger := log.New()
+	logger.SetHandler(log.FuncHandler(func(r *log.Record) error {
+		if strings.Contains(r.Msg, "limit of temporary files is disabled") {
+			ignored++
+		}
+		if strings.Contains(r.Msg, "temporary files reached the limit") {
+			loads++
+		}
+		return nil
+	}))
+	tempFilesLimitIgnored = sync.Once{}
+	cfg := testSendersConfig()
+	cfg.Logger = logger
+	cfg.MaxTempFilesSize = 200
+	require.NoError(t, SpawnRecoverSendersStage(context.Background(), cfg, &StageState{Stage: stages.Senders}, tx, config, 0, t.TempDir()))
+
+	// the limit is reported as disabled and the senders are loaded at the end
+	assert.Equal(t, 1, ignored)
+	assert.Zero(t, loads)
+	for n := uint64(1); n <= 20; n++ {
+		hash := common.Hash{byte(n), byte(n >> 8), 1}
+		assert.Equal(t, expect[n], rawdb.ReadSenders(tx, hash, n), "block %d", n)
+	}
+}
+
+func TestSendersStageRestartAfterPartialLoad(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+	expect := writeSendersTestChain(t, db, config, 50, 3)
+
+	// interrupt the stage when the second part of senders is being loaded, the first one is loaded already
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	loads := 0
+	logger := log.New()
+	logger.SetHandler(log.FuncHandler(func(r *log.Record) error {
+		if strings.Contains(r.Msg, "temporary files reached the limit") {
+			if loads++; loads == 2 {
+				cancel()
+			}
+		}
+		return nil
+	}))
+	cfg := testSendersConfig()
+	cfg.Logger = logger
+	cfg.MaxTempFilesSize = 600
+	err := SpawnRecoverSendersStage(ctx, cfg, &StageState{Stage: stages.Senders}, db, config, 0, "")
+	require.True(t, errors.Is(err, common.ErrStopped), "unexpected error %v", err)
+	progress, _, err := stages.GetStageProgress(db, stages.Senders)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), progress)
+	var partial int
+	require.NoError(t, db.Walk(dbutils.Senders, nil, 0, func(k, v []byte) (bool, error) {
+		partial++
+		return true, nil
+	}))
+	require.NotZero(t, partial, "first part must be loaded before the interruption")
+
+	// restart loads the same blocks again, they must not get senders twice
+	cfg.Logger = nil
+	require.NoError(t, SpawnRecoverSendersStage(context.Background(), cfg, &StageState{Stage: stages.Senders}, db, config, 0, ""))
+	var stored int
+	require.NoError(t, db.Walk(dbutils.Senders, nil, 0, func(k, v []byte) (bool, error) {
+		stored++
+		n := binary.BigEndian.Uint64(k[:8])
+		assert.Equal(t, len(expect[n])*common.AddressLength, len(v), "block %d", n)
+		return true, nil
+	}))
+	assert.Equal(t, 50, stored)
+	for n := uint64(1); n <= 50; n++ {
+		hash := common.Hash{byte(n), byte(n >> 8), 1}
+		assert.Equal(t, expect[n], rawdb.ReadSenders(db, hash, n), "block %d", n)
+	}
+}
+
+func TestStagedSyncSendersConfig(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	defer db.Close()
+	config := params.AllEthashProtocolChanges
+
+	writeSendersTestChain(t, db, config, 5, 1)
+	var messages int
+	logger := log.New()
+	logger.SetHandler(log.FuncHandler(func(r *log.Record) error {
+		messages++
+		return nil
+	}))
+	sync := New(DefaultStages(), DefaultUnwindOrder())
+	sync.Senders.Logger = logger
+	state, err := sync.Prepare(nil, config, nil, nil, db, db, "", ethdb.DefaultStorageMode, t.TempDir(), false, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+	stage, err := state.StageByID(stages.Senders)
+	require.NoError(t, err)
+	require.NoError(t, stage.ExecFunc(&StageState{Stage: stages.Senders}, nil))
+
+	// the stage logged through the configured logger and recovered the senders
+	assert.NotZero(t, messages)
+	progress, _, err := stages.GetStageProgress(db, stages.Senders)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), progress)
+}
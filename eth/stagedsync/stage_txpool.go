@@ -12,6 +12,7 @@ import (
 	"github.com/ledgerwatch/turbo-geth/core/types"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
 	"github.com/ledgerwatch/turbo-geth/log"
+	"github.com/ledgerwatch/turbo-geth/params"
 	"github.com/ledgerwatch/turbo-geth/rlp"
 )
 
@@ -108,13 +109,13 @@ func incrementalTxPoolUpdate(from, to uint64, pool *core.TxPool, db ethdb.Getter
 	return nil
 }
 
-func unwindTxPool(u *UnwindState, s *StageState, db ethdb.GetterPutter, pool *core.TxPool, quitCh <-chan struct{}) error {
+func unwindTxPool(u *UnwindState, s *StageState, db ethdb.GetterPutter, pool *core.TxPool, config *params.ChainConfig, quitCh <-chan struct{}) error {
 	if u.UnwindPoint >= s.BlockNumber {
 		s.Done()
 		return nil
 	}
 	if pool != nil && pool.IsStarted() {
-		if err := unwindTxPoolUpdate(u.UnwindPoint, s.BlockNumber, pool, db, quitCh); err != nil {
+		if err := unwindTxPoolUpdate(u.UnwindPoint, s.BlockNumber, pool, db, config, quitCh); err != nil {
 			return err
 		}
 		pending, queued := pool.Stats()
@@ -126,7 +127,7 @@ func unwindTxPool(u *UnwindState, s *StageState, db ethdb.GetterPutter, pool *co
 	return nil
 }
 
-func unwindTxPoolUpdate(from, to uint64, pool *core.TxPool, db ethdb.Getter, quitCh <-chan struct{}) error {
+func unwindTxPoolUpdate(from, to uint64, pool *core.TxPool, db ethdb.Getter, config *params.ChainConfig, quitCh <-chan struct{}) error {
 	headHash := rawdb.ReadCanonicalHash(db, from)
 	headHeader := rawdb.ReadHeader(db, headHash, from)
 	pool.ResetHead(headHeader.GasLimit, from)
@@ -205,7 +206,15 @@ func unwindTxPoolUpdate(from, to uint64, pool *core.TxPool, db ethdb.Getter, qui
 		if err := rlp.Decode(bytes.NewReader(bodyRlp), body); err != nil {
 			return false, fmt.Errorf("unwind TxPoolUpdate: invalid block body RLP: %w", err)
 		}
-		body.SendersToTxs(senders[blockNumber-from-1])
+		blockSenders := senders[blockNumber-from-1]
+		if blockSenders == nil { // not stored, senders of pruned blocks are recovered from the body
+			if blockSenders, err = rawdb.ReadSendersOrRecover(db, config, blockHash, blockNumber); err != nil {
+				return false, fmt.Errorf("unwind TxPoolUpdate: %w", err)
+			}
+		}
+		if err := applySenders(blockNumber, body, blockSenders); err != nil {
+			return false, fmt.Errorf("unwind TxPoolUpdate: %w", err)
+		}
 		txsToInject = append(txsToInject, body.Transactions...)
 		return true, nil
 	}); err != nil {
@@ -3,9 +3,9 @@ package stagedsync
 import (
 	"time"
 
+	"github.com/ledgerwatch/turbo-geth/common"
 	"github.com/ledgerwatch/turbo-geth/core"
 	"github.com/ledgerwatch/turbo-geth/core/vm"
-	"github.com/ledgerwatch/turbo-geth/crypto/secp256k1"
 	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
 	"github.com/ledgerwatch/turbo-geth/params"
@@ -34,6 +34,7 @@ type StageParameters struct {
 	poolStart        func() error
 	changeSetHook    ChangeSetHook
 	prefetchedBlocks *PrefetchedBlocks
+	senders          Stage3Config
 }
 
 // StageBuilder represent an object to create a single stage for staged sync
@@ -111,21 +112,11 @@ func DefaultStages() StageBuilders {
 					ID:          stages.Senders,
 					Description: "Recover senders from tx signatures",
 					ExecFunc: func(s *StageState, u Unwinder) error {
-						const batchSize = 10000
-						const blockSize = 4096
-						n := secp256k1.NumOfContexts() // we can only be as parallels as our crypto library supports
-
-						cfg := Stage3Config{
-							BatchSize:       batchSize,
-							BlockSize:       blockSize,
-							BufferSize:      (blockSize * 10 / 20) * 10000, // 20*4096
-							StartTrace:      false,
-							Prof:            false,
-							NumOfGoroutines: n,
-							ReadChLen:       4,
-							Now:             time.Now(),
-						}
-						return SpawnRecoverSendersStage(cfg, s, world.TX, world.chainConfig, 0, world.datadir, world.QuitCh)
+						cfg := world.senders
+						cfg.Now = time.Now()
+						ctx, cancel := common.ContextFromQuitCh(world.QuitCh)
+						defer cancel()
+						return SpawnRecoverSendersStage(ctx, cfg, s, world.TX, world.chainConfig, 0, world.datadir)
 					},
 					UnwindFunc: func(u *UnwindState, s *StageState) error {
 						return UnwindSendersStage(u, world.TX)
@@ -256,7 +247,7 @@ func DefaultStages() StageBuilders {
 						return spawnTxPool(s, world.TX, world.txPool, world.poolStart, world.QuitCh)
 					},
 					UnwindFunc: func(u *UnwindState, s *StageState) error {
-						return unwindTxPool(u, s, world.TX, world.txPool, world.QuitCh)
+						return unwindTxPool(u, s, world.TX, world.txPool, world.chainConfig, world.QuitCh)
 					},
 				}
 			},
@@ -11,13 +11,16 @@ const prof = false // whether to profile
 
 type StagedSync struct {
 	PrefetchedBlocks *PrefetchedBlocks
-	stageBuilders    StageBuilders
-	unwindOrder      UnwindOrder
+	// Senders configures the senders stage, it may be changed before Prepare
+	Senders       Stage3Config
+	stageBuilders StageBuilders
+	unwindOrder   UnwindOrder
 }
 
 func New(stages StageBuilders, unwindOrder UnwindOrder) *StagedSync {
 	return &StagedSync{
 		PrefetchedBlocks: NewPrefetchedBlocks(),
+		Senders:          DefaultStage3Config(),
 		stageBuilders:    stages,
 		unwindOrder:      unwindOrder,
 	}
@@ -58,6 +61,7 @@ func (stagedSync *StagedSync) Prepare(
 			changeSetHook:    changeSetHook,
 			hdd:              hdd,
 			prefetchedBlocks: stagedSync.PrefetchedBlocks,
+			senders:          stagedSync.Senders,
 		},
 	)
 	state := NewState(stages)
@@ -0,0 +1,32 @@
+package stages
+
+import (
+	"sync"
+	"time"
+)
+
+// Throughput is a snapshot of how fast a stage is processing blocks in this process
+type Throughput struct {
+	BlocksPerSecond float64
+	TxsPerSecond    float64
+	Updated         time.Time // zero if the stage never reported its throughput
+}
+
+var (
+	throughputLock sync.RWMutex
+	throughputs    = map[string]Throughput{}
+)
+
+// SetThroughput publishes the current throughput of the stage, it's called by the stage while it runs
+func SetThroughput(stage SyncStage, t Throughput) {
+	throughputLock.Lock()
+	defer throughputLock.Unlock()
+	throughputs[string(stage)] = t
+}
+
+// GetThroughput returns the last throughput published by the stage
+func GetThroughput(stage SyncStage) Throughput {
+	throughputLock.RLock()
+	defer throughputLock.RUnlock()
+	return throughputs[string(stage)]
+}
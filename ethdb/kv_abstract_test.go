@@ -105,7 +105,7 @@ func setupDatabases(f ethdb.BucketConfigsFunc) (writeDBs []ethdb.KV, readDBs []e
 
 	grpcServer := grpc.NewServer()
 	go func() {
-		remote.RegisterKVService(grpcServer, remote.NewKVService(remotedbserver.NewKvServer(writeDBs[1])))
+		remote.RegisterKVService(grpcServer, remote.NewKVService(remotedbserver.NewKvServer(writeDBs[1], remotedbserver.MaxTxTTL)))
 		if err := grpcServer.Serve(conn); err != nil {
 			log.Error("private RPC server fail", "err", err)
 		}
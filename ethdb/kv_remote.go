@@ -8,16 +8,21 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/c2h5oh/datasize"
+	"github.com/golang/snappy"
 	"github.com/ledgerwatch/turbo-geth/common"
 	"github.com/ledgerwatch/turbo-geth/common/dbutils"
 	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
 	"github.com/ledgerwatch/turbo-geth/log"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 )
 
@@ -35,6 +40,8 @@ type remoteOpts struct {
 	DialAddress string
 	inMemConn   *bufconn.Listener // for tests
 	bucketsCfg  BucketConfigsFunc
+	authToken   string
+	valueCodec  remote.ValueCodec
 }
 
 type RemoteKV struct {
@@ -44,6 +51,8 @@ type RemoteKV struct {
 	conn     *grpc.ClientConn
 	log      log.Logger
 	buckets  dbutils.BucketsCfg
+
+	noSeekExact int32 // set atomically when the server predates SeekExact RPC, then Get uses Seek stream
 }
 
 type remoteTx struct {
@@ -60,6 +69,7 @@ type remoteCursor struct {
 	prefix             []byte
 	stream             remote.KV_SeekClient
 	streamCancelFn     context.CancelFunc // this function needs to be called to close the stream
+	batch              []*remote.Pair     // pairs received from server but not yet returned by Next
 	tx                 *remoteTx
 	bucketName         string
 	bucketCfg          dbutils.BucketConfigItem
@@ -90,11 +100,34 @@ func (opts remoteOpts) WithBucketsConfig(f BucketConfigsFunc) remoteOpts {
 	return opts
 }
 
+// WithAuthToken - token is sent with every request, server started with the same token accepts only such requests
+func (opts remoteOpts) WithAuthToken(token string) remoteOpts {
+	opts.authToken = token
+	return opts
+}
+
+// WithValueCodec - server will compress big values by given codec, it saves bandwidth for buckets with big values
+func (opts remoteOpts) WithValueCodec(codec remote.ValueCodec) remoteOpts {
+	opts.valueCodec = codec
+	return opts
+}
+
 func (opts remoteOpts) InMem(listener *bufconn.Listener) remoteOpts {
 	opts.inMemConn = listener
 	return opts
 }
 
+// unixSocketScheme - prefix of the private API addresses which are paths of unix domain sockets, e.g. unix:///run/tg.sock
+const unixSocketScheme = "unix://"
+
+// NetworkAddress - splits the private API address into network and address, as net.Listen and net.Dial take them
+func NetworkAddress(addr string) (network, address string) {
+	if strings.HasPrefix(addr, unixSocketScheme) {
+		return "unix", strings.TrimPrefix(addr, unixSocketScheme)
+	}
+	return "tcp", addr
+}
+
 func (opts remoteOpts) Open(certFile, keyFile, caCert string) (KV, Backend, error) {
 	var dialOpts []grpc.DialOption
 	if certFile == "" {
@@ -142,10 +175,19 @@ func (opts remoteOpts) Open(certFile, keyFile, caCert string) (KV, Backend, erro
 		}
 	}
 
+	if opts.authToken != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(bearerToken(opts.authToken)))
+	}
+
 	if opts.inMemConn != nil {
 		dialOpts = append(dialOpts, grpc.WithContextDialer(func(ctx context.Context, url string) (net.Conn, error) {
 			return opts.inMemConn.Dial()
 		}))
+	} else if network, address := NetworkAddress(opts.DialAddress); network == "unix" {
+		dialOpts = append(dialOpts, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, address)
+		}))
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -179,6 +221,16 @@ func (opts remoteOpts) Open(certFile, keyFile, caCert string) (KV, Backend, erro
 	return db, eth, nil
 }
 
+// bearerToken - implements credentials.PerRPCCredentials
+type bearerToken string
+
+func (t bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity - token is allowed without TLS, for example when private api listens on localhost
+func (t bearerToken) RequireTransportSecurity() bool { return false }
+
 func (opts remoteOpts) MustOpen() (KV, Backend) {
 	db, txPool, err := opts.Open("", "", "")
 	if err != nil {
@@ -270,6 +322,23 @@ func (tx *remoteTx) BucketSize(name string) (uint64, error) {
 }
 
 func (tx *remoteTx) Get(bucket string, key []byte) (val []byte, err error) {
+	if atomic.LoadInt32(&tx.db.noSeekExact) == 0 {
+		reply, err := tx.db.remoteKV.SeekExact(tx.ctx, &remote.SeekExactRequest{BucketName: bucket, Key: key})
+		if status.Code(err) != codes.Unimplemented {
+			if err != nil {
+				return nil, err
+			}
+			if !reply.Found {
+				return nil, nil
+			}
+			if reply.Value == nil { // empty value is decoded as nil, but key exists
+				return []byte{}, nil
+			}
+			return reply.Value, nil
+		}
+		atomic.StoreInt32(&tx.db.noSeekExact, 1)
+	}
+
 	c := tx.Cursor(bucket)
 	defer func() {
 		if v, ok := c.(*remoteCursor); ok {
@@ -330,6 +399,7 @@ func (c *remoteCursor) Seek(seek []byte) ([]byte, []byte, error) {
 		c.streamCancelFn() // This will close the stream and free resources
 		c.stream = nil
 		c.streamingRequested = false
+		c.batch = nil
 	}
 	c.initialized = true
 
@@ -343,17 +413,17 @@ func (c *remoteCursor) Seek(seek []byte) ([]byte, []byte, error) {
 	if err != nil {
 		return []byte{}, nil, err
 	}
-	err = c.stream.Send(&remote.SeekRequest{BucketName: c.bucketName, SeekKey: seek, Prefix: c.prefix, StartSreaming: false})
+	err = c.stream.Send(&remote.SeekRequest{BucketName: c.bucketName, SeekKey: seek, Prefix: c.prefix, StartStreaming: false, ValueCodec: c.tx.db.opts.valueCodec})
 	if err != nil {
 		return []byte{}, nil, err
 	}
 
-	pair, err := c.stream.Recv()
+	pair, err := recvPair(c.stream)
 	if err != nil {
 		return []byte{}, nil, err
 	}
 
-	return pair.Key, pair.Value, nil
+	return unpackPair(pair)
 }
 
 // Next - returns next data element from server, request streaming (if configured by user)
@@ -365,17 +435,60 @@ func (c *remoteCursor) Next() ([]byte, []byte, error) {
 	// if streaming not requested, server will send data only when remoteKV send message to bi-directional channel
 	if !c.streamingRequested {
 		doStream := c.prefetch > 0
-		if err := c.stream.Send(&remote.SeekRequest{StartSreaming: doStream}); err != nil {
+		if err := c.stream.Send(&remote.SeekRequest{StartStreaming: doStream, BatchSize: c.prefetch}); err != nil {
 			return []byte{}, nil, err
 		}
 		c.streamingRequested = doStream
 	}
 
-	pair, err := c.stream.Recv()
+	if len(c.batch) > 0 {
+		pair := c.batch[0]
+		c.batch = c.batch[1:]
+		return unpackPair(pair)
+	}
+
+	pair, err := recvPair(c.stream)
 	if err != nil {
 		return []byte{}, nil, err
 	}
-	return pair.Key, pair.Value, nil
+	if len(pair.Batch) > 0 {
+		c.batch = pair.Batch[1:]
+		return unpackPair(pair.Batch[0])
+	}
+	return unpackPair(pair)
+}
+
+// recvPair - receives one Seek message, joining parts of a value which server had to split
+func recvPair(stream remote.KV_SeekClient) (*remote.Pair, error) {
+	pair, err := stream.Recv()
+	if err != nil || !pair.Continued {
+		return pair, err
+	}
+	value := common.CopyBytes(pair.Value)
+	for pair.Continued {
+		if pair, err = stream.Recv(); err != nil {
+			return nil, err
+		}
+		value = append(value, pair.Value...)
+	}
+	pair.Value = value
+	return pair, nil
+}
+
+// unpackPair - decodes value if server compressed it
+func unpackPair(pair *remote.Pair) ([]byte, []byte, error) {
+	switch pair.ValueCodec {
+	case remote.ValueCodec_NONE:
+		return pair.Key, pair.Value, nil
+	case remote.ValueCodec_SNAPPY:
+		v, err := snappy.Decode(nil, pair.Value)
+		if err != nil {
+			return []byte{}, nil, fmt.Errorf("decode value of key %x: %w", pair.Key, err)
+		}
+		return pair.Key, v, nil
+	default:
+		return []byte{}, nil, fmt.Errorf("unknown value codec %s of key %x", pair.ValueCodec, pair.Key)
+	}
 }
 
 func (c *remoteCursor) Last() ([]byte, []byte, error) {
@@ -387,6 +500,7 @@ func (c *remoteCursor) Close() {
 		c.streamCancelFn()
 		c.stream = nil
 		c.streamingRequested = false
+		c.batch = nil
 	}
 }
 
@@ -424,6 +538,7 @@ func (c *remoteCursorDupSort) SeekBothRange(key, value []byte) ([]byte, []byte,
 		c.streamCancelFn() // This will close the stream and free resources
 		c.stream = nil
 		c.streamingRequested = false
+		c.batch = nil
 	}
 	c.initialized = true
 
@@ -436,17 +551,17 @@ func (c *remoteCursorDupSort) SeekBothRange(key, value []byte) ([]byte, []byte,
 			return []byte{}, nil, err
 		}
 	}
-	err = c.stream.Send(&remote.SeekRequest{BucketName: c.bucketName, SeekKey: key, SeekValue: value, Prefix: c.prefix, StartSreaming: false})
+	err = c.stream.Send(&remote.SeekRequest{BucketName: c.bucketName, SeekKey: key, SeekValue: value, Prefix: c.prefix, StartStreaming: false, ValueCodec: c.tx.db.opts.valueCodec})
 	if err != nil {
 		return []byte{}, nil, err
 	}
 
-	pair, err := c.stream.Recv()
+	pair, err := recvPair(c.stream)
 	if err != nil {
 		return []byte{}, nil, err
 	}
 
-	return pair.Key, pair.Value, nil
+	return unpackPair(pair)
 }
 
 func (c *remoteCursorDupSort) DeleteExact(k1, k2 []byte) error      { panic("not supported") }
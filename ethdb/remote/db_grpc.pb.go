@@ -17,7 +17,9 @@ const _ = grpc.SupportPackageIsVersion7
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type DBClient interface {
+	// returns size of the database files in bytes, UNIMPLEMENTED if database doesn't report it
 	Size(ctx context.Context, in *SizeRequest, opts ...grpc.CallOption) (*SizeReply, error)
+	// returns size of pages used by the bucket in bytes, NOT_FOUND for unknown bucket
 	BucketSize(ctx context.Context, in *BucketSizeRequest, opts ...grpc.CallOption) (*BucketSizeReply, error)
 }
 
@@ -60,7 +62,9 @@ func (c *dBClient) BucketSize(ctx context.Context, in *BucketSizeRequest, opts .
 // RegisterDBService is called.  Any unassigned fields will result in the
 // handler for that method returning an Unimplemented error.
 type DBService struct {
-	Size       func(context.Context, *SizeRequest) (*SizeReply, error)
+	// returns size of the database files in bytes, UNIMPLEMENTED if database doesn't report it
+	Size func(context.Context, *SizeRequest) (*SizeReply, error)
+	// returns size of pages used by the bucket in bytes, NOT_FOUND for unknown bucket
 	BucketSize func(context.Context, *BucketSizeRequest) (*BucketSizeReply, error)
 }
 
@@ -152,6 +156,8 @@ func NewDBService(s interface{}) *DBService {
 // definition, which is not a backward-compatible change.  For this reason,
 // use of this type is not recommended.
 type UnstableDBService interface {
+	// returns size of the database files in bytes, UNIMPLEMENTED if database doesn't report it
 	Size(context.Context, *SizeRequest) (*SizeReply, error)
+	// returns size of pages used by the bucket in bytes, NOT_FOUND for unknown bucket
 	BucketSize(context.Context, *BucketSizeRequest) (*BucketSizeReply, error)
 }
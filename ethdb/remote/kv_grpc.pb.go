@@ -20,8 +20,25 @@ type KVClient interface {
 	// open a cursor on given position of given bucket
 	// if streaming requested - streams all data: stops if client's buffer is full, resumes when client read enough from buffer
 	// if streaming not requested - streams next data only when clients sends message to bi-directional channel
-	// no full consistency guarantee - server implementation can close/open underlying db transaction at any time
+	// no full consistency guarantee - server implementation can close/open underlying db transaction at any time,
+	// then keys added/removed by concurrent writers may appear/disappear in the middle of stream. Use SeekRequest.consistent to avoid it.
 	Seek(ctx context.Context, opts ...grpc.CallOption) (KV_SeekClient, error)
+	// returns value of exactly given key, without opening a stream
+	SeekExact(ctx context.Context, in *SeekExactRequest, opts ...grpc.CallOption) (*SeekExactReply, error)
+	// returns values of given key from several buckets, all read from one db snapshot
+	MultiSeekExact(ctx context.Context, in *MultiSeekExactRequest, opts ...grpc.CallOption) (*MultiSeekExactReply, error)
+	// returns first pair of given bucket, without opening a stream
+	First(ctx context.Context, in *FirstRequest, opts ...grpc.CallOption) (*EdgeReply, error)
+	// returns last pair of given bucket, without opening a stream
+	Last(ctx context.Context, in *LastRequest, opts ...grpc.CallOption) (*EdgeReply, error)
+	// returns amount of keys with given prefix, without sending them
+	Count(ctx context.Context, in *CountRequest, opts ...grpc.CallOption) (*CountReply, error)
+	// returns saved progress of given sync stage, without knowing how it's encoded in the db
+	StageProgress(ctx context.Context, in *StageProgressRequest, opts ...grpc.CallOption) (*StageProgressReply, error)
+	// returns current throughput of given sync stage running in the server process, for tuning it from outside
+	StageThroughput(ctx context.Context, in *StageThroughputRequest, opts ...grpc.CallOption) (*StageThroughputReply, error)
+	// streams recovered senders of blocks in given range ordered by block number and transaction index, one message per transaction
+	ExportSenders(ctx context.Context, in *ExportSendersRequest, opts ...grpc.CallOption) (KV_ExportSendersClient, error)
 }
 
 type kVClient struct {
@@ -69,6 +86,134 @@ func (x *kVSeekClient) Recv() (*Pair, error) {
 	return m, nil
 }
 
+var kVSeekExactStreamDesc = &grpc.StreamDesc{
+	StreamName: "SeekExact",
+}
+
+func (c *kVClient) SeekExact(ctx context.Context, in *SeekExactRequest, opts ...grpc.CallOption) (*SeekExactReply, error) {
+	out := new(SeekExactReply)
+	err := c.cc.Invoke(ctx, "/remote.KV/SeekExact", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var kVMultiSeekExactStreamDesc = &grpc.StreamDesc{
+	StreamName: "MultiSeekExact",
+}
+
+func (c *kVClient) MultiSeekExact(ctx context.Context, in *MultiSeekExactRequest, opts ...grpc.CallOption) (*MultiSeekExactReply, error) {
+	out := new(MultiSeekExactReply)
+	err := c.cc.Invoke(ctx, "/remote.KV/MultiSeekExact", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var kVFirstStreamDesc = &grpc.StreamDesc{
+	StreamName: "First",
+}
+
+func (c *kVClient) First(ctx context.Context, in *FirstRequest, opts ...grpc.CallOption) (*EdgeReply, error) {
+	out := new(EdgeReply)
+	err := c.cc.Invoke(ctx, "/remote.KV/First", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var kVLastStreamDesc = &grpc.StreamDesc{
+	StreamName: "Last",
+}
+
+func (c *kVClient) Last(ctx context.Context, in *LastRequest, opts ...grpc.CallOption) (*EdgeReply, error) {
+	out := new(EdgeReply)
+	err := c.cc.Invoke(ctx, "/remote.KV/Last", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var kVCountStreamDesc = &grpc.StreamDesc{
+	StreamName: "Count",
+}
+
+func (c *kVClient) Count(ctx context.Context, in *CountRequest, opts ...grpc.CallOption) (*CountReply, error) {
+	out := new(CountReply)
+	err := c.cc.Invoke(ctx, "/remote.KV/Count", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var kVStageProgressStreamDesc = &grpc.StreamDesc{
+	StreamName: "StageProgress",
+}
+
+func (c *kVClient) StageProgress(ctx context.Context, in *StageProgressRequest, opts ...grpc.CallOption) (*StageProgressReply, error) {
+	out := new(StageProgressReply)
+	err := c.cc.Invoke(ctx, "/remote.KV/StageProgress", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var kVStageThroughputStreamDesc = &grpc.StreamDesc{
+	StreamName: "StageThroughput",
+}
+
+func (c *kVClient) StageThroughput(ctx context.Context, in *StageThroughputRequest, opts ...grpc.CallOption) (*StageThroughputReply, error) {
+	out := new(StageThroughputReply)
+	err := c.cc.Invoke(ctx, "/remote.KV/StageThroughput", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var kVExportSendersStreamDesc = &grpc.StreamDesc{
+	StreamName:    "ExportSenders",
+	ServerStreams: true,
+}
+
+func (c *kVClient) ExportSenders(ctx context.Context, in *ExportSendersRequest, opts ...grpc.CallOption) (KV_ExportSendersClient, error) {
+	stream, err := c.cc.NewStream(ctx, kVExportSendersStreamDesc, "/remote.KV/ExportSenders", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &kVExportSendersClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type KV_ExportSendersClient interface {
+	Recv() (*SenderReply, error)
+	grpc.ClientStream
+}
+
+type kVExportSendersClient struct {
+	grpc.ClientStream
+}
+
+func (x *kVExportSendersClient) Recv() (*SenderReply, error) {
+	m := new(SenderReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // KVService is the service API for KV service.
 // Fields should be assigned to their respective handler implementations only before
 // RegisterKVService is called.  Any unassigned fields will result in the
@@ -77,8 +222,25 @@ type KVService struct {
 	// open a cursor on given position of given bucket
 	// if streaming requested - streams all data: stops if client's buffer is full, resumes when client read enough from buffer
 	// if streaming not requested - streams next data only when clients sends message to bi-directional channel
-	// no full consistency guarantee - server implementation can close/open underlying db transaction at any time
+	// no full consistency guarantee - server implementation can close/open underlying db transaction at any time,
+	// then keys added/removed by concurrent writers may appear/disappear in the middle of stream. Use SeekRequest.consistent to avoid it.
 	Seek func(KV_SeekServer) error
+	// returns value of exactly given key, without opening a stream
+	SeekExact func(context.Context, *SeekExactRequest) (*SeekExactReply, error)
+	// returns values of given key from several buckets, all read from one db snapshot
+	MultiSeekExact func(context.Context, *MultiSeekExactRequest) (*MultiSeekExactReply, error)
+	// returns first pair of given bucket, without opening a stream
+	First func(context.Context, *FirstRequest) (*EdgeReply, error)
+	// returns last pair of given bucket, without opening a stream
+	Last func(context.Context, *LastRequest) (*EdgeReply, error)
+	// returns amount of keys with given prefix, without sending them
+	Count func(context.Context, *CountRequest) (*CountReply, error)
+	// returns saved progress of given sync stage, without knowing how it's encoded in the db
+	StageProgress func(context.Context, *StageProgressRequest) (*StageProgressReply, error)
+	// returns current throughput of given sync stage running in the server process, for tuning it from outside
+	StageThroughput func(context.Context, *StageThroughputRequest) (*StageThroughputReply, error)
+	// streams recovered senders of blocks in given range ordered by block number and transaction index, one message per transaction
+	ExportSenders func(*ExportSendersRequest, KV_ExportSendersServer) error
 }
 
 func (s *KVService) seek(_ interface{}, stream grpc.ServerStream) error {
@@ -87,6 +249,156 @@ func (s *KVService) seek(_ interface{}, stream grpc.ServerStream) error {
 	}
 	return s.Seek(&kVSeekServer{stream})
 }
+func (s *KVService) seekExact(_ interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	if s.SeekExact == nil {
+		return nil, status.Errorf(codes.Unimplemented, "method SeekExact not implemented")
+	}
+	in := new(SeekExactRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return s.SeekExact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     s,
+		FullMethod: "/remote.KV/SeekExact",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.SeekExact(ctx, req.(*SeekExactRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+func (s *KVService) multiSeekExact(_ interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	if s.MultiSeekExact == nil {
+		return nil, status.Errorf(codes.Unimplemented, "method MultiSeekExact not implemented")
+	}
+	in := new(MultiSeekExactRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return s.MultiSeekExact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     s,
+		FullMethod: "/remote.KV/MultiSeekExact",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.MultiSeekExact(ctx, req.(*MultiSeekExactRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+func (s *KVService) first(_ interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	if s.First == nil {
+		return nil, status.Errorf(codes.Unimplemented, "method First not implemented")
+	}
+	in := new(FirstRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return s.First(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     s,
+		FullMethod: "/remote.KV/First",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.First(ctx, req.(*FirstRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+func (s *KVService) last(_ interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	if s.Last == nil {
+		return nil, status.Errorf(codes.Unimplemented, "method Last not implemented")
+	}
+	in := new(LastRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return s.Last(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     s,
+		FullMethod: "/remote.KV/Last",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.Last(ctx, req.(*LastRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+func (s *KVService) count(_ interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	if s.Count == nil {
+		return nil, status.Errorf(codes.Unimplemented, "method Count not implemented")
+	}
+	in := new(CountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return s.Count(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     s,
+		FullMethod: "/remote.KV/Count",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.Count(ctx, req.(*CountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+func (s *KVService) stageProgress(_ interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	if s.StageProgress == nil {
+		return nil, status.Errorf(codes.Unimplemented, "method StageProgress not implemented")
+	}
+	in := new(StageProgressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return s.StageProgress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     s,
+		FullMethod: "/remote.KV/StageProgress",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.StageProgress(ctx, req.(*StageProgressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+func (s *KVService) stageThroughput(_ interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	if s.StageThroughput == nil {
+		return nil, status.Errorf(codes.Unimplemented, "method StageThroughput not implemented")
+	}
+	in := new(StageThroughputRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return s.StageThroughput(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     s,
+		FullMethod: "/remote.KV/StageThroughput",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.StageThroughput(ctx, req.(*StageThroughputRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+func (s *KVService) exportSenders(_ interface{}, stream grpc.ServerStream) error {
+	if s.ExportSenders == nil {
+		return status.Errorf(codes.Unimplemented, "method ExportSenders not implemented")
+	}
+	m := new(ExportSendersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.ExportSenders(m, &kVExportSendersServer{stream})
+}
 
 type KV_SeekServer interface {
 	Send(*Pair) error
@@ -110,11 +422,53 @@ func (x *kVSeekServer) Recv() (*SeekRequest, error) {
 	return m, nil
 }
 
+type KV_ExportSendersServer interface {
+	Send(*SenderReply) error
+	grpc.ServerStream
+}
+
+type kVExportSendersServer struct {
+	grpc.ServerStream
+}
+
+func (x *kVExportSendersServer) Send(m *SenderReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // RegisterKVService registers a service implementation with a gRPC server.
 func RegisterKVService(s grpc.ServiceRegistrar, srv *KVService) {
 	sd := grpc.ServiceDesc{
 		ServiceName: "remote.KV",
-		Methods:     []grpc.MethodDesc{},
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: "SeekExact",
+				Handler:    srv.seekExact,
+			},
+			{
+				MethodName: "MultiSeekExact",
+				Handler:    srv.multiSeekExact,
+			},
+			{
+				MethodName: "First",
+				Handler:    srv.first,
+			},
+			{
+				MethodName: "Last",
+				Handler:    srv.last,
+			},
+			{
+				MethodName: "Count",
+				Handler:    srv.count,
+			},
+			{
+				MethodName: "StageProgress",
+				Handler:    srv.stageProgress,
+			},
+			{
+				MethodName: "StageThroughput",
+				Handler:    srv.stageThroughput,
+			},
+		},
 		Streams: []grpc.StreamDesc{
 			{
 				StreamName:    "Seek",
@@ -122,6 +476,11 @@ func RegisterKVService(s grpc.ServiceRegistrar, srv *KVService) {
 				ServerStreams: true,
 				ClientStreams: true,
 			},
+			{
+				StreamName:    "ExportSenders",
+				Handler:       srv.exportSenders,
+				ServerStreams: true,
+			},
 		},
 		Metadata: "remote/kv.proto",
 	}
@@ -140,6 +499,46 @@ func NewKVService(s interface{}) *KVService {
 	if h, ok := s.(interface{ Seek(KV_SeekServer) error }); ok {
 		ns.Seek = h.Seek
 	}
+	if h, ok := s.(interface {
+		SeekExact(context.Context, *SeekExactRequest) (*SeekExactReply, error)
+	}); ok {
+		ns.SeekExact = h.SeekExact
+	}
+	if h, ok := s.(interface {
+		MultiSeekExact(context.Context, *MultiSeekExactRequest) (*MultiSeekExactReply, error)
+	}); ok {
+		ns.MultiSeekExact = h.MultiSeekExact
+	}
+	if h, ok := s.(interface {
+		First(context.Context, *FirstRequest) (*EdgeReply, error)
+	}); ok {
+		ns.First = h.First
+	}
+	if h, ok := s.(interface {
+		Last(context.Context, *LastRequest) (*EdgeReply, error)
+	}); ok {
+		ns.Last = h.Last
+	}
+	if h, ok := s.(interface {
+		Count(context.Context, *CountRequest) (*CountReply, error)
+	}); ok {
+		ns.Count = h.Count
+	}
+	if h, ok := s.(interface {
+		StageProgress(context.Context, *StageProgressRequest) (*StageProgressReply, error)
+	}); ok {
+		ns.StageProgress = h.StageProgress
+	}
+	if h, ok := s.(interface {
+		StageThroughput(context.Context, *StageThroughputRequest) (*StageThroughputReply, error)
+	}); ok {
+		ns.StageThroughput = h.StageThroughput
+	}
+	if h, ok := s.(interface {
+		ExportSenders(*ExportSendersRequest, KV_ExportSendersServer) error
+	}); ok {
+		ns.ExportSenders = h.ExportSenders
+	}
 	return ns
 }
 
@@ -151,6 +550,166 @@ type UnstableKVService interface {
 	// open a cursor on given position of given bucket
 	// if streaming requested - streams all data: stops if client's buffer is full, resumes when client read enough from buffer
 	// if streaming not requested - streams next data only when clients sends message to bi-directional channel
-	// no full consistency guarantee - server implementation can close/open underlying db transaction at any time
+	// no full consistency guarantee - server implementation can close/open underlying db transaction at any time,
+	// then keys added/removed by concurrent writers may appear/disappear in the middle of stream. Use SeekRequest.consistent to avoid it.
 	Seek(KV_SeekServer) error
+	// returns value of exactly given key, without opening a stream
+	SeekExact(context.Context, *SeekExactRequest) (*SeekExactReply, error)
+	// returns values of given key from several buckets, all read from one db snapshot
+	MultiSeekExact(context.Context, *MultiSeekExactRequest) (*MultiSeekExactReply, error)
+	// returns first pair of given bucket, without opening a stream
+	First(context.Context, *FirstRequest) (*EdgeReply, error)
+	// returns last pair of given bucket, without opening a stream
+	Last(context.Context, *LastRequest) (*EdgeReply, error)
+	// returns amount of keys with given prefix, without sending them
+	Count(context.Context, *CountRequest) (*CountReply, error)
+	// returns saved progress of given sync stage, without knowing how it's encoded in the db
+	StageProgress(context.Context, *StageProgressRequest) (*StageProgressReply, error)
+	// returns current throughput of given sync stage running in the server process, for tuning it from outside
+	StageThroughput(context.Context, *StageThroughputRequest) (*StageThroughputReply, error)
+	// streams recovered senders of blocks in given range ordered by block number and transaction index, one message per transaction
+	ExportSenders(*ExportSendersRequest, KV_ExportSendersServer) error
+}
+
+// MutableKVClient is the client API for MutableKV service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MutableKVClient interface {
+	Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutReply, error)
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteReply, error)
+}
+
+type mutableKVClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMutableKVClient(cc grpc.ClientConnInterface) MutableKVClient {
+	return &mutableKVClient{cc}
+}
+
+var mutableKVPutStreamDesc = &grpc.StreamDesc{
+	StreamName: "Put",
+}
+
+func (c *mutableKVClient) Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutReply, error) {
+	out := new(PutReply)
+	err := c.cc.Invoke(ctx, "/remote.MutableKV/Put", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var mutableKVDeleteStreamDesc = &grpc.StreamDesc{
+	StreamName: "Delete",
+}
+
+func (c *mutableKVClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteReply, error) {
+	out := new(DeleteReply)
+	err := c.cc.Invoke(ctx, "/remote.MutableKV/Delete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MutableKVService is the service API for MutableKV service.
+// Fields should be assigned to their respective handler implementations only before
+// RegisterMutableKVService is called.  Any unassigned fields will result in the
+// handler for that method returning an Unimplemented error.
+type MutableKVService struct {
+	Put    func(context.Context, *PutRequest) (*PutReply, error)
+	Delete func(context.Context, *DeleteRequest) (*DeleteReply, error)
+}
+
+func (s *MutableKVService) put(_ interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	if s.Put == nil {
+		return nil, status.Errorf(codes.Unimplemented, "method Put not implemented")
+	}
+	in := new(PutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return s.Put(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     s,
+		FullMethod: "/remote.MutableKV/Put",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.Put(ctx, req.(*PutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+func (s *MutableKVService) delete(_ interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	if s.Delete == nil {
+		return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+	}
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return s.Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     s,
+		FullMethod: "/remote.MutableKV/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RegisterMutableKVService registers a service implementation with a gRPC server.
+func RegisterMutableKVService(s grpc.ServiceRegistrar, srv *MutableKVService) {
+	sd := grpc.ServiceDesc{
+		ServiceName: "remote.MutableKV",
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: "Put",
+				Handler:    srv.put,
+			},
+			{
+				MethodName: "Delete",
+				Handler:    srv.delete,
+			},
+		},
+		Streams:  []grpc.StreamDesc{},
+		Metadata: "remote/kv.proto",
+	}
+
+	s.RegisterService(&sd, nil)
+}
+
+// NewMutableKVService creates a new MutableKVService containing the
+// implemented methods of the MutableKV service in s.  Any unimplemented
+// methods will result in the gRPC server returning an UNIMPLEMENTED status to the client.
+// This includes situations where the method handler is misspelled or has the wrong
+// signature.  For this reason, this function should be used with great care and
+// is not recommended to be used by most users.
+func NewMutableKVService(s interface{}) *MutableKVService {
+	ns := &MutableKVService{}
+	if h, ok := s.(interface {
+		Put(context.Context, *PutRequest) (*PutReply, error)
+	}); ok {
+		ns.Put = h.Put
+	}
+	if h, ok := s.(interface {
+		Delete(context.Context, *DeleteRequest) (*DeleteReply, error)
+	}); ok {
+		ns.Delete = h.Delete
+	}
+	return ns
+}
+
+// UnstableMutableKVService is the service API for MutableKV service.
+// New methods may be added to this interface if they are added to the service
+// definition, which is not a backward-compatible change.  For this reason,
+// use of this type is not recommended.
+type UnstableMutableKVService interface {
+	Put(context.Context, *PutRequest) (*PutReply, error)
+	Delete(context.Context, *DeleteRequest) (*DeleteReply, error)
 }
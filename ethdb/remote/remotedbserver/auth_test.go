@@ -0,0 +1,44 @@
+package remotedbserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+)
+
+func TestAuthToken(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	writeSequence(t, kv, dbutils.BlockBodyPrefix, 10)
+
+	addr := freeAddr(t)
+	cfg := DefaultGrpcConfig(addr)
+	cfg.AuthToken = "secret"
+	grpcServer, err := StartGrpc(kv, nil, cfg)
+	require.NoError(t, err)
+	defer grpcServer.Stop()
+
+	// read - does unary (Get) and stream (Seek) calls
+	read := func(token string) error {
+		remoteKV, _, err := ethdb.NewRemote().Path(addr).WithAuthToken(token).Open("", "", "")
+		require.NoError(t, err)
+		defer remoteKV.Close()
+		return remoteKV.View(context.Background(), func(tx ethdb.Tx) error {
+			if _, err := tx.Get(dbutils.BlockBodyPrefix, []byte{0, 0, 0, 1}); err != nil {
+				return err
+			}
+			_, _, err := tx.Cursor(dbutils.BlockBodyPrefix).First()
+			return err
+		})
+	}
+
+	require.NoError(t, read("secret"))
+	require.Equal(t, codes.Unauthenticated, status.Code(read("")))
+	require.Equal(t, codes.Unauthenticated, status.Code(read("wrong")))
+}
@@ -0,0 +1,121 @@
+package remotedbserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/core/rawdb"
+	"github.com/ledgerwatch/turbo-geth/core/types"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
+)
+
+func TestSeekValueCodec(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	values := map[string][]byte{
+		string([]byte{1}): {1, 2, 3},                                                                                                                                           // too small to compress
+		string([]byte{2}): bytes.Repeat([]byte{7}, 1024),                                                                                                                       // compressible
+		string([]byte{3}): common.Hex2Bytes("5e1d3a76fbf824220eafc8c6bd2c5c8cd67c1e8e41c0de8e1a4f9e7c7d7b6e9fb1f2a3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f8"), // random-like, doesn't shrink
+	}
+	require.NoError(t, kv.Update(context.Background(), func(tx ethdb.Tx) error {
+		for k, v := range values {
+			if err := tx.Cursor(dbutils.BlockBodyPrefix).Put([]byte(k), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	conn := serveInMem(t, NewKvServer(kv, MaxTxTTL))
+
+	for _, codec := range []remote.ValueCodec{remote.ValueCodec_NONE, remote.ValueCodec_SNAPPY} {
+		remoteKV, _, err := ethdb.NewRemote().InMem(conn).WithValueCodec(codec).Open("", "", "")
+		require.NoError(t, err)
+		require.NoError(t, remoteKV.View(context.Background(), func(tx ethdb.Tx) error {
+			n := 0
+			c := tx.Cursor(dbutils.BlockBodyPrefix)
+			for k, v, err := c.First(); k != nil; k, v, err = c.Next() {
+				require.NoError(t, err)
+				require.Equal(t, values[string(k)], v, "codec %s, key %x", codec, k)
+				n++
+			}
+			require.Equal(t, len(values), n)
+			return nil
+		}))
+		remoteKV.Close()
+	}
+
+	// server compresses only the value which shrinks
+	stream, err := dialInMem(t, conn).Seek(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, StartStreaming: true, ValueCodec: remote.ValueCodec_SNAPPY}))
+	var codecs []remote.ValueCodec
+	for {
+		pair, err := stream.Recv()
+		require.NoError(t, err)
+		if pair.Key == nil {
+			break
+		}
+		codecs = append(codecs, pair.ValueCodec)
+	}
+	require.Equal(t, []remote.ValueCodec{remote.ValueCodec_NONE, remote.ValueCodec_SNAPPY, remote.ValueCodec_NONE}, codecs)
+}
+
+// BenchmarkSeekReceipts - compares amount of bytes sent over the wire to scan receipts bucket
+func BenchmarkSeekReceipts(b *testing.B) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	db := ethdb.NewObjectDatabase(kv)
+	transferTopic := common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	for block := uint64(0); block < 100; block++ {
+		receipts := make(types.Receipts, 50)
+		for i := range receipts {
+			from, to := common.Hash{byte(i)}, common.Hash{byte(block)}
+			receipts[i] = &types.Receipt{
+				Status:            types.ReceiptStatusSuccessful,
+				CumulativeGasUsed: uint64(i+1) * 50000,
+				Logs: []*types.Log{{
+					Address: common.Address{1, byte(i % 5)}, // few popular tokens
+					Topics:  []common.Hash{transferTopic, from, to},
+					Data:    common.LeftPadBytes(uint64Bytes(block*100+uint64(i)), 32),
+				}},
+			}
+		}
+		rawdb.WriteReceipts(db, common.Hash{byte(block)}, block, receipts)
+	}
+	client := dialInMem(b, serveInMem(b, NewKvServer(kv, MaxTxTTL)))
+
+	for _, codec := range []remote.ValueCodec{remote.ValueCodec_NONE, remote.ValueCodec_SNAPPY} {
+		b.Run(codec.String(), func(b *testing.B) {
+			var wireBytes int
+			for i := 0; i < b.N; i++ {
+				wireBytes = 0
+				stream, err := client.Seek(context.Background())
+				require.NoError(b, err)
+				require.NoError(b, stream.Send(&remote.SeekRequest{BucketName: dbutils.BlockReceiptsPrefix, StartStreaming: true, ValueCodec: codec}))
+				for {
+					pair, err := stream.Recv()
+					require.NoError(b, err)
+					wireBytes += proto.Size(pair)
+					if pair.Key == nil {
+						break
+					}
+				}
+			}
+			b.ReportMetric(float64(wireBytes), "wire-bytes/scan")
+		})
+	}
+}
+
+func uint64Bytes(v uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, v)
+	return buf
+}
@@ -3,22 +3,39 @@ package remotedbserver
 import (
 	"context"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
 	"github.com/ledgerwatch/turbo-geth/ethdb"
 	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
 )
 
+// DBServer - serves the DB service: sizes of the database and of its buckets.
+// It never modifies the database, buckets are read in read-only transactions.
 type DBServer struct {
 	remote.UnstableDBService // must be embedded to have forward compatible implementations.
 
-	kv ethdb.KV
+	kv             ethdb.KV
+	allowedBuckets map[string]struct{} // nil means all buckets are allowed
+	readTxs        *readTxLimiter      // see KvServer.WithMaxReadTxs
 }
 
 func NewDBServer(kv ethdb.KV) *DBServer {
 	return &DBServer{kv: kv}
 }
 
+// WithAllowedBuckets - restricts buckets which sizes are reported, see KvServer.WithAllowedBuckets
+func (s *DBServer) WithAllowedBuckets(names ...string) *DBServer {
+	s.allowedBuckets = bucketSet(names)
+	return s
+}
+
 func (s *DBServer) Size(ctx context.Context, in *remote.SizeRequest) (*remote.SizeReply, error) {
-	sz, err := s.kv.(ethdb.HasStats).DiskSize(ctx)
+	stats, ok := s.kv.(ethdb.HasStats)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "database doesn't report its size")
+	}
+	sz, err := stats.DiskSize(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -26,6 +43,14 @@ func (s *DBServer) Size(ctx context.Context, in *remote.SizeRequest) (*remote.Si
 }
 
 func (s *DBServer) BucketSize(ctx context.Context, in *remote.BucketSizeRequest) (*remote.BucketSizeReply, error) {
+	if err := checkBucket(s.kv, s.allowedBuckets, in.BucketName); err != nil {
+		return nil, err
+	}
+	release, err := s.readTxs.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	out := &remote.BucketSizeReply{}
 	if err := s.kv.View(ctx, func(tx ethdb.Tx) error {
 		sz, err := tx.BucketSize(in.BucketName)
@@ -0,0 +1,145 @@
+package remotedbserver
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
+)
+
+// writeDetectingKV - counts attempts to open write transactions
+type writeDetectingKV struct {
+	ethdb.KV
+	writes int32
+}
+
+func (kv *writeDetectingKV) Update(ctx context.Context, f func(tx ethdb.Tx) error) error {
+	atomic.AddInt32(&kv.writes, 1)
+	return kv.KV.Update(ctx, f)
+}
+
+func (kv *writeDetectingKV) Begin(ctx context.Context, parent ethdb.Tx, writable bool) (ethdb.Tx, error) {
+	if writable {
+		atomic.AddInt32(&kv.writes, 1)
+	}
+	return kv.KV.Begin(ctx, parent, writable)
+}
+
+func serveDBInMem(t *testing.T, dbSrv *DBServer) remote.DBClient {
+	conn := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	remote.RegisterDBService(grpcServer, remote.NewDBService(dbSrv))
+	go func() {
+		_ = grpcServer.Serve(conn)
+	}()
+	t.Cleanup(func() {
+		grpcServer.Stop()
+		_ = conn.Close()
+	})
+	return remote.NewDBClient(dialConnInMem(t, conn))
+}
+
+func TestDBServer(t *testing.T) {
+	db := ethdb.NewLMDB().InMem().MustOpen()
+	defer db.Close()
+	writeSequence(t, db, dbutils.HeaderPrefix, 10_000)
+	kv := &writeDetectingKV{KV: db}
+	client := serveDBInMem(t, NewDBServer(db))
+
+	t.Run("Size", func(t *testing.T) {
+		expected, err := db.(ethdb.HasStats).DiskSize(context.Background())
+		require.NoError(t, err)
+		reply, err := client.Size(context.Background(), &remote.SizeRequest{})
+		require.NoError(t, err)
+		require.Equal(t, expected, reply.Size)
+
+		_, err = serveDBInMem(t, NewDBServer(kv)).Size(context.Background(), &remote.SizeRequest{})
+		require.Equal(t, codes.Unimplemented, status.Code(err), "wrapper hides database stats")
+	})
+
+	t.Run("BucketSize", func(t *testing.T) {
+		client := serveDBInMem(t, NewDBServer(kv))
+		var expected uint64
+		require.NoError(t, db.View(context.Background(), func(tx ethdb.Tx) error {
+			var err error
+			expected, err = tx.BucketSize(dbutils.HeaderPrefix)
+			return err
+		}))
+		require.NotZero(t, expected)
+		reply, err := client.BucketSize(context.Background(), &remote.BucketSizeRequest{BucketName: dbutils.HeaderPrefix})
+		require.NoError(t, err)
+		require.Equal(t, expected, reply.Size)
+
+		reply, err = client.BucketSize(context.Background(), &remote.BucketSizeRequest{BucketName: dbutils.BlockBodyPrefix})
+		require.NoError(t, err)
+		require.Zero(t, reply.Size, "empty bucket")
+
+		_, err = client.BucketSize(context.Background(), &remote.BucketSizeRequest{BucketName: "no_such_bucket"})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("AllowedBuckets", func(t *testing.T) {
+		client := serveDBInMem(t, NewDBServer(kv).WithAllowedBuckets(dbutils.HeaderPrefix))
+		_, err := client.BucketSize(context.Background(), &remote.BucketSizeRequest{BucketName: dbutils.HeaderPrefix})
+		require.NoError(t, err)
+		_, err = client.BucketSize(context.Background(), &remote.BucketSizeRequest{BucketName: dbutils.BlockBodyPrefix})
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+		_, err = client.BucketSize(context.Background(), &remote.BucketSizeRequest{BucketName: "no_such_bucket"})
+		require.Equal(t, codes.PermissionDenied, status.Code(err), "unknown bucket isn't revealed")
+	})
+
+	require.Zero(t, atomic.LoadInt32(&kv.writes), "DB service must not open write transactions")
+}
+
+func TestDBServerSharesReadTxLimit(t *testing.T) {
+	db := ethdb.NewLMDB().InMem().MustOpen()
+	defer db.Close()
+	writeSequence(t, db, dbutils.HeaderPrefix, 10)
+
+	addr := freeAddr(t)
+	cfg := DefaultGrpcConfig(addr)
+	require.Zero(t, cfg.MaxReadTxs, "read transactions are unlimited by default")
+	cfg.MaxReadTxs, cfg.MaxReadTxsWait = 1, 50*time.Millisecond
+	grpcServer, err := StartGrpc(db, nil, cfg)
+	require.NoError(t, err)
+	defer grpcServer.Stop()
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	dbClient := remote.NewDBClient(conn)
+	healthClient := grpc_health_v1.NewHealthClient(conn)
+
+	// the Seek stream holds the only slot until it's closed
+	streamCtx, closeStream := context.WithCancel(context.Background())
+	stream, err := remote.NewKVClient(conn).Seek(streamCtx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: dbutils.HeaderPrefix}))
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	_, err = dbClient.BucketSize(context.Background(), &remote.BucketSizeRequest{BucketName: dbutils.HeaderPrefix})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	resp, err := healthClient.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, resp.Status)
+
+	closeStream()
+	require.Eventually(t, func() bool {
+		_, err = dbClient.BucketSize(context.Background(), &remote.BucketSizeRequest{BucketName: dbutils.HeaderPrefix})
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	resp, err = healthClient.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, resp.Status)
+}
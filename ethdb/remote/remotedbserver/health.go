@@ -0,0 +1,54 @@
+package remotedbserver
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/log"
+)
+
+// healthCheckedServices - services which can be asked about, empty name means the whole server
+var healthCheckedServices = map[string]struct{}{
+	"":                  {},
+	"remote.KV":         {},
+	"remote.MutableKV":  {},
+	"remote.DB":         {},
+	"remote.ETHBACKEND": {},
+}
+
+// HealthServer - implements standard gRPC health checking protocol,
+// server is SERVING only if it can open read transaction
+type HealthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+
+	kv      ethdb.KV
+	log     log.Logger
+	readTxs *readTxLimiter // see KvServer.WithMaxReadTxs
+}
+
+func NewHealthServer(kv ethdb.KV) *HealthServer {
+	return &HealthServer{kv: kv, log: log.Root()}
+}
+
+func (s *HealthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	if _, ok := healthCheckedServices[req.Service]; !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown service: %q", req.Service)
+	}
+	release, err := s.readTxs.acquire(ctx)
+	if err != nil {
+		s.log.Warn("Private RPC server health check failed", "err", err)
+		return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_NOT_SERVING}, nil
+	}
+	defer release()
+	tx, err := s.kv.Begin(ctx, nil, false)
+	if err != nil {
+		s.log.Warn("Private RPC server health check failed", "err", err)
+		return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_NOT_SERVING}, nil
+	}
+	tx.Rollback()
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
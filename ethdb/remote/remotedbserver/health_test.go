@@ -0,0 +1,65 @@
+package remotedbserver
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+)
+
+// unavailableKV - fails to begin transactions while unavailable is set
+type unavailableKV struct {
+	ethdb.KV
+	unavailable int32
+}
+
+func (kv *unavailableKV) Begin(ctx context.Context, parent ethdb.Tx, writable bool) (ethdb.Tx, error) {
+	if atomic.LoadInt32(&kv.unavailable) == 1 {
+		return nil, errors.New("db is unavailable")
+	}
+	return kv.KV.Begin(ctx, parent, writable)
+}
+
+func TestHealthCheck(t *testing.T) {
+	db := ethdb.NewLMDB().InMem().MustOpen()
+	defer db.Close()
+	kv := &unavailableKV{KV: db}
+
+	addr := freeAddr(t)
+	cfg := DefaultGrpcConfig(addr)
+	cfg.AuthToken = "secret" // health check doesn't need token
+	grpcServer, err := StartGrpc(kv, nil, cfg)
+	require.NoError(t, err)
+	defer grpcServer.Stop()
+
+	clientConn, err := grpc.Dial(addr, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer clientConn.Close()
+	client := grpc_health_v1.NewHealthClient(clientConn)
+
+	check := func(service string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+		resp, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		return resp.Status
+	}
+
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, check(""))
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, check("remote.KV"))
+
+	atomic.StoreInt32(&kv.unavailable, 1)
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, check(""))
+
+	atomic.StoreInt32(&kv.unavailable, 0)
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, check(""))
+
+	_, err = client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,73 @@
+package remotedbserver
+
+import (
+	"time"
+
+	"github.com/ledgerwatch/turbo-geth/metrics"
+)
+
+// metrics are registered on first use, when metrics are enabled, to not register nil metrics at init
+const (
+	servedKeysPrefix  = "remote/kv/served/keys/"  // + bucket, keys sent to clients by Seek, SeekExact and MultiSeekExact
+	servedBytesPrefix = "remote/kv/served/bytes/" // + bucket, bytes of keys and values (before compression)
+
+	// lifetime of Seek read transactions by how they ended, helps to tune tx TTL
+	seekTxTTLTimer     = "remote/kv/seek/tx/ttl"  // rolled back to reopen after tx TTL
+	seekTxDoneTimer    = "remote/kv/seek/tx/done" // stream ended
+	seekTxReopensCount = "remote/kv/seek/tx/reopens"
+)
+
+// bucketCounters - counters of one bucket, resolved once per request to not look them up on every key
+type bucketCounters struct {
+	keys, bytes metrics.Counter
+}
+
+// newBucketCounters - returns nil if metrics are disabled
+func newBucketCounters(bucket string) *bucketCounters {
+	if !metrics.Enabled {
+		return nil
+	}
+	return &bucketCounters{
+		keys:  metrics.GetOrRegisterCounter(servedKeysPrefix+bucket, nil),
+		bytes: metrics.GetOrRegisterCounter(servedBytesPrefix+bucket, nil),
+	}
+}
+
+func (c *bucketCounters) add(k, v []byte) {
+	if c == nil {
+		return
+	}
+	c.keys.Inc(1)
+	c.bytes.Inc(int64(len(k) + len(v)))
+}
+
+// seekTxTimer - measures lifetime of read transactions of one Seek stream
+type seekTxTimer struct {
+	started time.Time
+}
+
+// newSeekTxTimer - returns nil if metrics are disabled
+func newSeekTxTimer() *seekTxTimer {
+	if !metrics.Enabled {
+		return nil
+	}
+	return &seekTxTimer{started: time.Now()}
+}
+
+// reopened - transaction was rolled back because of tx TTL, a new one is opened
+func (t *seekTxTimer) reopened() {
+	if t == nil {
+		return
+	}
+	now := time.Now()
+	metrics.GetOrRegisterTimer(seekTxTTLTimer, nil).Update(now.Sub(t.started))
+	metrics.GetOrRegisterCounter(seekTxReopensCount, nil).Inc(1)
+	t.started = now
+}
+
+func (t *seekTxTimer) done() {
+	if t == nil {
+		return
+	}
+	metrics.GetOrRegisterTimer(seekTxDoneTimer, nil).UpdateSince(t.started)
+}
@@ -0,0 +1,77 @@
+package remotedbserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
+	"github.com/ledgerwatch/turbo-geth/metrics"
+)
+
+func TestBucketMetrics(t *testing.T) {
+	enabled := metrics.Enabled
+	metrics.Enabled = true
+	defer func() { metrics.Enabled = enabled }()
+
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	writeSequence(t, kv, dbutils.BlockBodyPrefix, 100)
+	client := dialInMem(t, serveInMem(t, NewKvServer(kv, MaxTxTTL)))
+
+	keys := metrics.GetOrRegisterCounter(servedKeysPrefix+dbutils.BlockBodyPrefix, nil)
+	bytes := metrics.GetOrRegisterCounter(servedBytesPrefix+dbutils.BlockBodyPrefix, nil)
+	keysBefore, bytesBefore := keys.Count(), bytes.Count()
+
+	stream, err := client.Seek(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, StartStreaming: true}))
+	for {
+		pair, err := stream.Recv()
+		require.NoError(t, err)
+		if pair.Key == nil {
+			break
+		}
+	}
+	_, err = client.SeekExact(context.Background(), &remote.SeekExactRequest{BucketName: dbutils.BlockBodyPrefix, Key: []byte{0, 0, 0, 1}})
+	require.NoError(t, err)
+
+	require.Equal(t, int64(101), keys.Count()-keysBefore)
+	require.Equal(t, int64(101*8), bytes.Count()-bytesBefore)
+}
+
+// seekTxCounts - reads TTL reopens counter and amount of observed transactions which ended by TTL
+func seekTxCounts() (reopens, ttlEnded int64) {
+	return metrics.GetOrRegisterCounter(seekTxReopensCount, nil).Count(), metrics.GetOrRegisterTimer(seekTxTTLTimer, nil).Count()
+}
+
+func TestSeekTxMetrics(t *testing.T) {
+	enabled := metrics.Enabled
+	metrics.Enabled = true
+	defer func() { metrics.Enabled = enabled }()
+
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	writeSequence(t, kv, dbutils.BlockBodyPrefix, 100)
+	client := dialInMem(t, serveInMem(t, NewKvServer(kv, time.Nanosecond)))
+
+	reopensBefore, ttlEndedBefore := seekTxCounts()
+	stream, err := client.Seek(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, StartStreaming: true}))
+	for {
+		pair, err := stream.Recv()
+		require.NoError(t, err)
+		if pair.Key == nil {
+			break
+		}
+	}
+
+	reopens, ttlEnded := seekTxCounts()
+	require.Greater(t, reopens, reopensBefore)
+	require.Equal(t, reopens-reopensBefore, ttlEnded-ttlEndedBefore)
+}
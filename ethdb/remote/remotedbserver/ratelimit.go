@@ -0,0 +1,89 @@
+package remotedbserver
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// limitersTTL - limiters of peers which didn't send requests for this long are forgotten
+const limitersTTL = 10 * time.Minute
+
+// peerRateLimiter - token bucket per peer IP, unary call and opening of stream are counted as 1 request
+type peerRateLimiter struct {
+	limit rate.Limit
+	burst int
+
+	lock        sync.Mutex
+	limiters    map[string]*peerLimiter
+	lastCleanup time.Time
+}
+
+type peerLimiter struct {
+	*rate.Limiter
+	lastSeen time.Time
+}
+
+func newPeerRateLimiter(requestsPerSecond float64, burst int) *peerRateLimiter {
+	return &peerRateLimiter{
+		limit:       rate.Limit(requestsPerSecond),
+		burst:       burst,
+		limiters:    map[string]*peerLimiter{},
+		lastCleanup: time.Now(),
+	}
+}
+
+func (l *peerRateLimiter) allow(ctx context.Context) error {
+	key := "unknown"
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		key = p.Addr.String()
+		if host, _, err := net.SplitHostPort(key); err == nil { // new connections from same host share limit
+			key = host
+		}
+	}
+
+	now := time.Now()
+	l.lock.Lock()
+	if now.Sub(l.lastCleanup) > limitersTTL {
+		for k, pl := range l.limiters {
+			if now.Sub(pl.lastSeen) > limitersTTL {
+				delete(l.limiters, k)
+			}
+		}
+		l.lastCleanup = now
+	}
+	pl, ok := l.limiters[key]
+	if !ok {
+		pl = &peerLimiter{Limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.limiters[key] = pl
+	}
+	pl.lastSeen = now
+	allowed := pl.AllowN(now, 1)
+	l.lock.Unlock()
+
+	if !allowed {
+		return status.Errorf(codes.ResourceExhausted, "rate limit exceeded: %v requests per second, burst %d", l.limit, l.burst)
+	}
+	return nil
+}
+
+func (l *peerRateLimiter) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := l.allow(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (l *peerRateLimiter) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := l.allow(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
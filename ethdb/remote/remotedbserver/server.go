@@ -1,42 +1,181 @@
 package remotedbserver
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/golang/snappy"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
+	grpc_opentracing "github.com/grpc-ecosystem/go-grpc-middleware/tracing/opentracing"
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/ledgerwatch/lmdb-go/lmdb"
+	"github.com/opentracing/opentracing-go"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 
 	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
 	"github.com/ledgerwatch/turbo-geth/core"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
 	"github.com/ledgerwatch/turbo-geth/ethdb"
 	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
 	"github.com/ledgerwatch/turbo-geth/log"
 	"github.com/ledgerwatch/turbo-geth/metrics"
 )
 
+// MaxTxTTL is the default interval after which Seek rolls back and reopens
+// its read transaction, to avoid holding a long-lived reader.
 const MaxTxTTL = 30 * time.Second
 
+// DefaultStaleClientTimeout is how long Seek waits for a client to drain the stream,
+// before giving up and releasing the read transaction.
+const DefaultStaleClientTimeout = time.Minute
+
+// DefaultMaxReadTxs - read transactions aren't limited by default: every open Seek stream holds one,
+// so a limit may reject clients under normal load. 64 keeps the server well below LMDB's default limit
+// of 126 readers, leaving room for the node's own read transactions.
+const DefaultMaxReadTxs = 0
+
+// DefaultMaxReadTxsWait is how long a request waits for a free read transaction slot.
+const DefaultMaxReadTxsWait = 5 * time.Second
+
+var errStaleClient = errors.New("client didn't read or send data for too long")
+
 type KvServer struct {
 	remote.UnstableKVService // must be embedded to have forward compatible implementations.
 
-	kv ethdb.KV
+	kv                 ethdb.KV
+	log                log.Logger
+	writable           bool // allows MutableKV service, see NewKvServerRW
+	txTTL              time.Duration
+	staleClientTimeout time.Duration
+
+	readTxs *readTxLimiter // shared with DBServer and HealthServer by StartGrpc
+
+	allowedBuckets map[string]struct{} // nil means all buckets are allowed
+	tokens         *tokenSigner
+}
+
+// GrpcConfig - settings of private API server. Defaults are tuned for a node with limited resources,
+// a node serving many RPC clients may want more streams and bigger buffers.
+type GrpcConfig struct {
+	Addr      string
+	Creds     *credentials.TransportCredentials // nil means no transport security
+	AuthToken string                            // if not empty, clients must send it as a bearer token (see ethdb.remoteOpts.WithAuthToken)
+
+	NumStreamWorkers     uint32 // 0 means goroutine per stream
+	MaxConcurrentStreams uint32 // per client connection
+	ReadBufferSize       int
+	WriteBufferSize      int
+
+	TxTTL          time.Duration // see NewKvServer
+	Writable       bool          // see NewKvServerRW
+	AllowedBuckets []string      // see KvServer.WithAllowedBuckets
+
+	Logger             log.Logger    // messages of the server are tagged with its address, nil means the root logger
+	StaleClientTimeout time.Duration // see KvServer.WithStaleClientTimeout
+	MaxReadTxs         int           // see KvServer.WithMaxReadTxs
+	MaxReadTxsWait     time.Duration
+
+	RateLimit float64 // requests per second from one client host, 0 means unlimited
+	RateBurst int
+
+	// Tracer - if not nil, every request gets a span tagged with the bucket name and amount of read keys,
+	// the span continues the trace of the client if its metadata carries one
+	Tracer opentracing.Tracer
+}
+
+func DefaultGrpcConfig(addr string) GrpcConfig {
+	return GrpcConfig{
+		Addr:                 addr,
+		NumStreamWorkers:     20,   // reduce amount of goroutines
+		MaxConcurrentStreams: 40,   // to force clients reduce concurency level
+		ReadBufferSize:       1024, // reduce buffers to save mem
+		WriteBufferSize:      1024,
+		TxTTL:                MaxTxTTL,
+		StaleClientTimeout:   DefaultStaleClientTimeout,
+		MaxReadTxs:           DefaultMaxReadTxs,
+		MaxReadTxsWait:       DefaultMaxReadTxsWait,
+	}
+}
+
+func (cfg GrpcConfig) validate() error {
+	if cfg.MaxConcurrentStreams == 0 {
+		return fmt.Errorf("MaxConcurrentStreams must be positive")
+	}
+	if cfg.RateLimit < 0 || (cfg.RateLimit > 0 && cfg.RateBurst <= 0) {
+		return fmt.Errorf("rate limit must not be negative and needs positive burst: limit=%v, burst=%d", cfg.RateLimit, cfg.RateBurst)
+	}
+	if cfg.ReadBufferSize < 0 || cfg.WriteBufferSize < 0 {
+		return fmt.Errorf("buffer sizes must not be negative: read=%d, write=%d", cfg.ReadBufferSize, cfg.WriteBufferSize)
+	}
+	if cfg.MaxReadTxs < 0 {
+		return fmt.Errorf("MaxReadTxs must not be negative: %d", cfg.MaxReadTxs)
+	}
+	return nil
+}
+
+// listen - listens on TCP address or on unix domain socket if address starts with unix://, see ethdb.NetworkAddress.
+// Socket file left by a crashed node is replaced, socket file is removed when server stops.
+func listen(addr string) (net.Listener, error) {
+	network, address := ethdb.NetworkAddress(addr)
+	if network == "unix" {
+		if fi, err := os.Stat(address); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			if conn, err := net.Dial(network, address); err == nil {
+				conn.Close()
+				return nil, fmt.Errorf("socket is in use by another process")
+			}
+			if err := os.Remove(address); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return net.Listen(network, address)
 }
 
-func StartGrpc(kv ethdb.KV, eth core.Backend, addr string, creds *credentials.TransportCredentials) {
-	log.Info("Starting private RPC server", "on", addr)
-	lis, err := net.Listen("tcp", addr)
+// StartGrpc starts serving the private API in a background goroutine.
+// The returned server must be stopped by the caller, preferably with GracefulStop.
+func StartGrpc(kv ethdb.KV, eth core.Backend, cfg GrpcConfig) (*grpc.Server, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("private RPC server config: %w", err)
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = log.Root()
+	}
+	logger = logger.New("private_api", cfg.Addr)
+	logger.Info("Starting private RPC server", "max_streams", cfg.MaxConcurrentStreams)
+	lis, err := listen(cfg.Addr)
 	if err != nil {
-		log.Error("Could not create listener", "address", addr, "err", err)
-		return
+		return nil, fmt.Errorf("could not create listener: %w, addr=%s", err, cfg.Addr)
 	}
 
-	kvSrv := NewKvServer(kv)
-	dbSrv := NewDBServer(kv)
+	newKvServer := NewKvServer
+	if cfg.Writable {
+		newKvServer = NewKvServerRW
+	}
+	kvSrv := newKvServer(kv, cfg.TxTTL).
+		WithStaleClientTimeout(cfg.StaleClientTimeout).
+		WithMaxReadTxs(cfg.MaxReadTxs, cfg.MaxReadTxsWait).
+		WithAllowedBuckets(cfg.AllowedBuckets...).
+		WithLogger(logger)
+	dbSrv := NewDBServer(kv).WithAllowedBuckets(cfg.AllowedBuckets...)
+	dbSrv.readTxs = kvSrv.readTxs
 	ethBackendSrv := NewEthBackendServer(eth)
 	var (
 		streamInterceptors []grpc.StreamServerInterceptor
@@ -46,32 +185,41 @@ func StartGrpc(kv ethdb.KV, eth core.Backend, addr string, creds *credentials.Tr
 		streamInterceptors = append(streamInterceptors, grpc_prometheus.StreamServerInterceptor)
 		unaryInterceptors = append(unaryInterceptors, grpc_prometheus.UnaryServerInterceptor)
 	}
+	if cfg.Tracer != nil { // before other interceptors, then spans include time spent in them
+		streamInterceptors = append(streamInterceptors, grpc_opentracing.StreamServerInterceptor(grpc_opentracing.WithTracer(cfg.Tracer)))
+		unaryInterceptors = append(unaryInterceptors, grpc_opentracing.UnaryServerInterceptor(grpc_opentracing.WithTracer(cfg.Tracer)))
+	}
 	streamInterceptors = append(streamInterceptors, grpc_recovery.StreamServerInterceptor())
 	unaryInterceptors = append(unaryInterceptors, grpc_recovery.UnaryServerInterceptor())
-	var grpcServer *grpc.Server
-	if creds == nil {
-		grpcServer = grpc.NewServer(
-			grpc.NumStreamWorkers(20),  // reduce amount of goroutines
-			grpc.WriteBufferSize(1024), // reduce buffers to save mem
-			grpc.ReadBufferSize(1024),
-			grpc.MaxConcurrentStreams(40), // to force clients reduce concurency level
-			grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(streamInterceptors...)),
-			grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(unaryInterceptors...)),
-		)
-	} else {
-		grpcServer = grpc.NewServer(
-			grpc.NumStreamWorkers(20),  // reduce amount of goroutines
-			grpc.WriteBufferSize(1024), // reduce buffers to save mem
-			grpc.ReadBufferSize(1024),
-			grpc.MaxConcurrentStreams(40), // to force clients reduce concurency level
-			grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(streamInterceptors...)),
-			grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(unaryInterceptors...)),
-			grpc.Creds(*creds),
-		)
+	if cfg.RateLimit > 0 {
+		limiter := newPeerRateLimiter(cfg.RateLimit, cfg.RateBurst)
+		streamInterceptors = append(streamInterceptors, limiter.StreamInterceptor)
+		unaryInterceptors = append(unaryInterceptors, limiter.UnaryInterceptor)
+	}
+	if cfg.AuthToken != "" {
+		streamInterceptors = append(streamInterceptors, AuthStreamInterceptor(cfg.AuthToken))
+		unaryInterceptors = append(unaryInterceptors, AuthUnaryInterceptor(cfg.AuthToken))
+	}
+	opts := []grpc.ServerOption{
+		grpc.NumStreamWorkers(cfg.NumStreamWorkers),
+		grpc.WriteBufferSize(cfg.WriteBufferSize),
+		grpc.ReadBufferSize(cfg.ReadBufferSize),
+		grpc.MaxConcurrentStreams(cfg.MaxConcurrentStreams),
+		grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(streamInterceptors...)),
+		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(unaryInterceptors...)),
 	}
+	if cfg.Creds != nil {
+		opts = append(opts, grpc.Creds(*cfg.Creds))
+	}
+	grpcServer := grpc.NewServer(opts...)
 	remote.RegisterKVService(grpcServer, remote.NewKVService(kvSrv))
+	remote.RegisterMutableKVService(grpcServer, remote.NewMutableKVService(kvSrv))
 	remote.RegisterDBService(grpcServer, remote.NewDBService(dbSrv))
 	remote.RegisterETHBACKENDService(grpcServer, remote.NewETHBACKENDService(ethBackendSrv))
+	healthSrv := NewHealthServer(kv)
+	healthSrv.log = logger
+	healthSrv.readTxs = kvSrv.readTxs
+	grpc_health_v1.RegisterHealthServer(grpcServer, healthSrv)
 
 	if metrics.Enabled {
 		grpc_prometheus.Register(grpcServer)
@@ -79,20 +227,499 @@ func StartGrpc(kv ethdb.KV, eth core.Backend, addr string, creds *credentials.Tr
 
 	go func() {
 		if err := grpcServer.Serve(lis); err != nil {
-			log.Error("private RPC server fail", "err", err)
+			logger.Error("private RPC server fail", "err", err)
 		}
 	}()
+
+	return grpcServer, nil
+}
+
+// TLS - creates server credentials from PEM encoded cert/key files.
+// If caFile is given, clients must present a certificate signed by this CA (mutual TLS).
+func TLS(certFile, keyFile, caFile string) (credentials.TransportCredentials, error) {
+	if caFile == "" {
+		return credentials.NewServerTLSFromFile(certFile, keyFile)
+	}
+
+	peerCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load peer cert/key: %w", err)
+	}
+	caCert, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("read ca cert: %w", err)
+	}
+	caCertPool := x509.NewCertPool()
+	if !caCertPool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{peerCert},
+		ClientCAs:    caCertPool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}), nil
+}
+
+// NewKvServer creates a KV server. txTTL limits the lifetime of read transactions
+// opened by Seek; a non-positive value means MaxTxTTL.
+func NewKvServer(kv ethdb.KV, txTTL time.Duration) *KvServer {
+	if txTTL <= 0 {
+		txTTL = MaxTxTTL
+	}
+	return &KvServer{kv: kv, log: log.Root(), txTTL: txTTL, staleClientTimeout: DefaultStaleClientTimeout, tokens: newTokenSigner()}
+}
+
+// NewKvServerRW creates a KV server which also serves MutableKV service: writes of remote clients
+// are applied to kv. Use it only for trusted tooling (pruning, repair), never expose to untrusted clients.
+func NewKvServerRW(kv ethdb.KV, txTTL time.Duration) *KvServer {
+	s := NewKvServer(kv, txTTL)
+	s.writable = true
+	return s
+}
+
+// WithLogger - logger of server's messages, e.g. tagged with server address
+func (s *KvServer) WithLogger(logger log.Logger) *KvServer {
+	s.log = logger
+	return s
+}
+
+// WithStaleClientTimeout - non-positive value disables stale client protection
+func (s *KvServer) WithStaleClientTimeout(timeout time.Duration) *KvServer {
+	s.staleClientTimeout = timeout
+	return s
+}
+
+// WithMaxReadTxs - limits amount of concurrently open read transactions: Seek, SeekExact and Count
+// hold one slot for their whole lifetime (including tx reopens) and wait at most `wait` for a free slot,
+// then fail with codes.ResourceExhausted. Non-positive limit means unlimited.
+func (s *KvServer) WithMaxReadTxs(limit int, wait time.Duration) *KvServer {
+	s.readTxs = newReadTxLimiter(limit, wait)
+	return s
+}
+
+// WithResumeTokenKey - key to sign Seek resume tokens (see SeekRequest.resumeTokens). By default key is random,
+// then tokens are valid only until server restart. Servers sharing the key accept tokens of each other.
+func (s *KvServer) WithResumeTokenKey(key []byte) *KvServer {
+	s.tokens = &tokenSigner{key: common.CopyBytes(key)}
+	return s
+}
+
+// WithAllowedBuckets - restricts buckets accessible by clients, other buckets are rejected with codes.PermissionDenied.
+// Empty list allows all buckets.
+func (s *KvServer) WithAllowedBuckets(names ...string) *KvServer {
+	s.allowedBuckets = bucketSet(names)
+	return s
+}
+
+// bucketSet - set of the bucket names, nil for empty list
+func bucketSet(names []string) map[string]struct{} {
+	if len(names) == 0 {
+		return nil
+	}
+	set := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		set[name] = struct{}{}
+	}
+	return set
+}
+
+// acquireReadTx - blocks until a read transaction slot is free, returned func must be called to free it
+func (s *KvServer) acquireReadTx(ctx context.Context) (release func(), err error) {
+	return s.readTxs.acquire(ctx)
+}
+
+// readTxLimiter - semaphore of open read transactions, nil means unlimited
+type readTxLimiter struct {
+	slots chan struct{}
+	wait  time.Duration
+}
+
+func newReadTxLimiter(limit int, wait time.Duration) *readTxLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &readTxLimiter{slots: make(chan struct{}, limit), wait: wait}
+}
+
+// acquire - waits at most l.wait for a free slot, then fails with codes.ResourceExhausted
+func (l *readTxLimiter) acquire(ctx context.Context) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	release = func() { <-l.slots }
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	timer := time.NewTimer(l.wait)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		return nil, status.Errorf(codes.ResourceExhausted, "too many open read transactions: %d", cap(l.slots))
+	}
+}
+
+var errReadOnly = status.Error(codes.PermissionDenied, "server is read-only")
+
+func (s *KvServer) Put(ctx context.Context, in *remote.PutRequest) (*remote.PutReply, error) {
+	if !s.writable {
+		return nil, errReadOnly
+	}
+	if err := s.checkBucket(in.BucketName); err != nil {
+		return nil, err
+	}
+	if err := s.kv.Update(ctx, func(tx ethdb.Tx) error {
+		return tx.Cursor(in.BucketName).Put(in.Key, in.Value)
+	}); err != nil {
+		return nil, err
+	}
+	return &remote.PutReply{}, nil
+}
+
+func (s *KvServer) Delete(ctx context.Context, in *remote.DeleteRequest) (*remote.DeleteReply, error) {
+	if !s.writable {
+		return nil, errReadOnly
+	}
+	if err := s.checkBucket(in.BucketName); err != nil {
+		return nil, err
+	}
+	if err := s.kv.Update(ctx, func(tx ethdb.Tx) error {
+		return tx.Cursor(in.BucketName).Delete(in.Key)
+	}); err != nil {
+		return nil, err
+	}
+	return &remote.DeleteReply{}, nil
+}
+
+// checkBucket - not allowed or unknown bucket name is a client error, must not reach the db
+func (s *KvServer) checkBucket(name string) error {
+	return checkBucket(s.kv, s.allowedBuckets, name)
 }
 
-func NewKvServer(kv ethdb.KV) *KvServer {
-	return &KvServer{kv: kv}
+func checkBucket(kv ethdb.KV, allowedBuckets map[string]struct{}, name string) error {
+	if allowedBuckets != nil {
+		if _, ok := allowedBuckets[name]; !ok {
+			return status.Errorf(codes.PermissionDenied, "bucket is not allowed: %q", name)
+		}
+	}
+	if _, ok := kv.AllBuckets()[name]; !ok {
+		return status.Errorf(codes.NotFound, "bucket not found: %q", name)
+	}
+	return nil
+}
+
+// traceBucket - tags the span of the request with the bucket name, returns nil if requests aren't traced (see GrpcConfig.Tracer)
+func traceBucket(ctx context.Context, name string) opentracing.Span {
+	span := opentracing.SpanFromContext(ctx)
+	if span != nil {
+		span.SetTag("bucket", name)
+	}
+	return span
+}
+
+func (s *KvServer) SeekExact(ctx context.Context, in *remote.SeekExactRequest) (*remote.SeekExactReply, error) {
+	traceBucket(ctx, in.BucketName)
+	if err := s.checkBucket(in.BucketName); err != nil {
+		return nil, err
+	}
+	release, err := s.acquireReadTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	reply := &remote.SeekExactReply{}
+	if err := s.kv.View(ctx, func(tx ethdb.Tx) error {
+		v, err := tx.Get(in.BucketName, in.Key)
+		if err != nil {
+			return err
+		}
+		reply.Value, reply.Found = common.CopyBytes(v), v != nil
+		if reply.Found {
+			newBucketCounters(in.BucketName).add(in.Key, v)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// MultiSeekExact - reads one key from several buckets in one read transaction,
+// then values are consistent with each other (e.g. header, body and receipts of one block)
+func (s *KvServer) MultiSeekExact(ctx context.Context, in *remote.MultiSeekExactRequest) (*remote.MultiSeekExactReply, error) {
+	traceBucket(ctx, strings.Join(in.BucketNames, ","))
+	for _, name := range in.BucketNames {
+		if err := s.checkBucket(name); err != nil {
+			return nil, err
+		}
+	}
+	release, err := s.acquireReadTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	reply := &remote.MultiSeekExactReply{Values: make([]*remote.SeekExactReply, len(in.BucketNames))}
+	if err := s.kv.View(ctx, func(tx ethdb.Tx) error {
+		for i, name := range in.BucketNames {
+			v, err := tx.Get(name, in.Key)
+			if err != nil {
+				return err
+			}
+			reply.Values[i] = &remote.SeekExactReply{Value: common.CopyBytes(v), Found: v != nil}
+			if v != nil {
+				newBucketCounters(name).add(in.Key, v)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// First - returns the first pair of the bucket, e.g. the lowest stored block
+func (s *KvServer) First(ctx context.Context, in *remote.FirstRequest) (*remote.EdgeReply, error) {
+	return s.edge(ctx, in.BucketName, ethdb.Cursor.First)
+}
+
+// Last - returns the last pair of the bucket, e.g. the highest stored block
+func (s *KvServer) Last(ctx context.Context, in *remote.LastRequest) (*remote.EdgeReply, error) {
+	return s.edge(ctx, in.BucketName, ethdb.Cursor.Last)
+}
+
+func (s *KvServer) edge(ctx context.Context, bucketName string, position func(ethdb.Cursor) ([]byte, []byte, error)) (*remote.EdgeReply, error) {
+	traceBucket(ctx, bucketName)
+	if err := s.checkBucket(bucketName); err != nil {
+		return nil, err
+	}
+	release, err := s.acquireReadTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	reply := &remote.EdgeReply{}
+	if err := s.kv.View(ctx, func(tx ethdb.Tx) error {
+		k, v, err := position(tx.Cursor(bucketName))
+		if err != nil {
+			return err
+		}
+		if k == nil {
+			return nil
+		}
+		reply.Key, reply.Value, reply.Found = common.CopyBytes(k), common.CopyBytes(v), true
+		newBucketCounters(bucketName).add(k, v)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// Count - counts keys with given prefix. Like Seek, it reopens read transaction every txTTL,
+// then continues from the last counted key.
+func (s *KvServer) Count(ctx context.Context, in *remote.CountRequest) (*remote.CountReply, error) {
+	span := traceBucket(ctx, in.BucketName)
+	if err := s.checkBucket(in.BucketName); err != nil {
+		return nil, err
+	}
+	release, err := s.acquireReadTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	tx, err := s.kv.Begin(ctx, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { tx.Rollback() }()
+
+	txTicker := time.NewTicker(s.txTTL)
+	defer txTicker.Stop()
+
+	c := tx.Cursor(in.BucketName).Prefix(in.Prefix)
+	var count uint64
+	k, _, err := c.Seek(in.Prefix)
+	for k != nil {
+		if err != nil {
+			return nil, err
+		}
+		count++
+
+		select {
+		default:
+			k, _, err = c.Next()
+			continue
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-txTicker.C:
+		}
+
+		last := common.CopyBytes(k)
+		tx.Rollback()
+		if tx, err = s.kv.Begin(ctx, nil, false); err != nil {
+			return nil, err
+		}
+		c = tx.Cursor(in.BucketName).Prefix(in.Prefix)
+		k, _, err = c.Seek(last)
+		if err == nil && bytes.Equal(k, last) { // already counted
+			k, _, err = c.Next()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if span != nil {
+		span.SetTag("keys", count)
+	}
+	return &remote.CountReply{Count: count}, nil
+}
+
+// StageProgress - reads saved progress of the sync stage, so monitoring tools don't depend on its encoding in the db
+func (s *KvServer) StageProgress(ctx context.Context, in *remote.StageProgressRequest) (*remote.StageProgressReply, error) {
+	if in.Stage == "" {
+		return nil, status.Error(codes.InvalidArgument, "stage name is empty")
+	}
+	if err := s.checkBucket(dbutils.SyncStageProgress); err != nil {
+		return nil, err
+	}
+	release, err := s.acquireReadTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	progress, _, err := stages.GetStageProgress(ethdb.NewObjectDatabase(s.kv), stages.SyncStage(in.Stage))
+	if err != nil {
+		return nil, err
+	}
+	return &remote.StageProgressReply{Progress: progress}, nil
+}
+
+// StageThroughput - returns the throughput last published by the sync stage running in this process,
+// doesn't touch the db, so it's cheap enough to be polled by controllers tuning the stage
+func (s *KvServer) StageThroughput(ctx context.Context, in *remote.StageThroughputRequest) (*remote.StageThroughputReply, error) {
+	if in.Stage == "" {
+		return nil, status.Error(codes.InvalidArgument, "stage name is empty")
+	}
+	t := stages.GetThroughput(stages.SyncStage(in.Stage))
+	reply := &remote.StageThroughputReply{BlocksPerSecond: t.BlocksPerSecond, TxsPerSecond: t.TxsPerSecond}
+	if !t.Updated.IsZero() {
+		reply.Updated = t.Updated.UnixNano() / int64(time.Millisecond)
+	}
+	return reply, nil
+}
+
+// ExportSenders - streams recovered senders, so indexers don't depend on how the Senders bucket is encoded.
+// Like Count, it reopens read transaction every txTTL, then continues from the last exported block.
+func (s *KvServer) ExportSenders(in *remote.ExportSendersRequest, stream remote.KV_ExportSendersServer) error {
+	ctx := stream.Context()
+	span := traceBucket(ctx, dbutils.Senders)
+	if in.ToBlock != 0 && in.ToBlock < in.FromBlock {
+		return status.Errorf(codes.InvalidArgument, "toBlock %d is less than fromBlock %d", in.ToBlock, in.FromBlock)
+	}
+	if err := s.checkBucket(dbutils.Senders); err != nil {
+		return err
+	}
+	release, err := s.acquireReadTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	tx, err := s.kv.Begin(ctx, nil, false)
+	if err != nil {
+		return err
+	}
+	defer func() { tx.Rollback() }()
+
+	txTicker := time.NewTicker(s.txTTL)
+	defer txTicker.Stop()
+
+	var exported uint64
+	c := tx.Cursor(dbutils.Senders)
+	k, v, err := c.Seek(dbutils.EncodeBlockNumber(in.FromBlock))
+	for k != nil {
+		if err != nil {
+			return err
+		}
+		if len(k) != 8+common.HashLength {
+			return status.Errorf(codes.Internal, "unexpected senders key: %x", k)
+		}
+		blockNumber := binary.BigEndian.Uint64(k)
+		if in.ToBlock != 0 && blockNumber > in.ToBlock {
+			break
+		}
+		blockHash := common.CopyBytes(k[8:])
+		for i := 0; i+common.AddressLength <= len(v); i += common.AddressLength {
+			reply := &remote.SenderReply{BlockNumber: blockNumber, BlockHash: blockHash, TxIndex: uint32(i / common.AddressLength), Address: v[i : i+common.AddressLength]}
+			if err = stream.Send(reply); err != nil { // message is marshaled by Send, so v can be referenced
+				return err
+			}
+			exported++
+		}
+
+		select {
+		default:
+			k, v, err = c.Next()
+			continue
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-txTicker.C:
+		}
+
+		last := common.CopyBytes(k)
+		tx.Rollback()
+		if tx, err = s.kv.Begin(ctx, nil, false); err != nil {
+			return err
+		}
+		c = tx.Cursor(dbutils.Senders)
+		k, v, err = c.Seek(last)
+		if err == nil && bytes.Equal(k, last) { // already exported
+			k, v, err = c.Next()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if span != nil {
+		span.SetTag("keys", exported)
+	}
+	return nil
 }
 
 func (s *KvServer) Seek(stream remote.KV_SeekServer) error {
+	err := seekStatus(stream.Context(), s.serveSeek(stream))
+	if status.Code(err) == codes.Internal { // other codes are caused by client
+		s.log.Warn("Seek failed", "err", err)
+	}
+	return err
+}
+
+func (s *KvServer) serveSeek(stream remote.KV_SeekServer) error {
 	in, recvErr := stream.Recv()
 	if recvErr != nil {
+		if recvErr == io.EOF {
+			return errNoSeekRequest
+		}
 		return recvErr
 	}
+	span := traceBucket(stream.Context(), in.BucketName)
+	if err := s.checkBucket(in.BucketName); err != nil {
+		return err
+	}
+	resumeAfter, err := s.resumeAfter(in)
+	if err != nil {
+		return err
+	}
+	release, err := s.acquireReadTx(stream.Context())
+	if err != nil {
+		return err
+	}
+	defer release()
 	tx, err := s.kv.Begin(stream.Context(), nil, false)
 	if err != nil {
 		return err
@@ -101,19 +728,53 @@ func (s *KvServer) Seek(stream remote.KV_SeekServer) error {
 		tx.Rollback()
 	}
 	defer rollback()
+	txTimer := newSeekTxTimer()
+	defer txTimer.done()
 
-	bucketName, prefix := in.BucketName, in.Prefix // 'in' value will cahnge, but this params will immutable
+	bucketName, prefix, reverse, consistent, endKey := in.BucketName, in.Prefix, in.Reverse, in.Consistent, in.EndKey // 'in' value will cahnge, but this params will immutable
+	if reverse && len(endKey) > 0 {
+		return errReverseEndKey
+	}
 
 	var c ethdb.Cursor
+	// seek and next respect iteration direction, for DupSort only forward direction is supported
+	seek := func(key []byte) ([]byte, []byte, error) {
+		if reverse {
+			return seekReverse(c, prefix, key)
+		}
+		return c.Seek(key)
+	}
+	next := func() ([]byte, []byte, error) {
+		if reverse {
+			return prevWithPrefix(c, prefix)
+		}
+		return c.Next()
+	}
 
-	txTicker := time.NewTicker(MaxTxTTL)
+	txTicker := time.NewTicker(s.txTTL)
 	defer txTicker.Stop()
 
 	isDupsort := len(in.SeekValue) != 0
+	if isDupsort && s.kv.AllBuckets()[bucketName].Flags&lmdb.DupSort == 0 {
+		return errNotDupSort
+	}
+	if isDupsort && reverse {
+		return errReverseDupSort
+	}
+	if isDupsort && len(resumeAfter) > 0 {
+		return errResumeDupSort
+	}
 	var k, v []byte
 	if !isDupsort {
-		c = tx.Cursor(bucketName).Prefix(prefix)
-		k, v, err = c.Seek(in.SeekKey)
+		c = newSeekCursor(tx, bucketName, prefix, reverse)
+		if len(resumeAfter) > 0 {
+			k, v, err = seek(resumeAfter)
+			if err == nil && k != nil && bytes.Equal(k, resumeAfter) { // client already has it
+				k, v, err = next()
+			}
+		} else {
+			k, v, err = seek(in.SeekKey)
+		}
 		if err != nil {
 			return err
 		}
@@ -132,9 +793,37 @@ func (s *KvServer) Seek(stream remote.KV_SeekServer) error {
 		c = cd
 	}
 
+	guarded := newStaleClientGuard(stream, s.staleClientTimeout)
+	defer guarded.close()
+	sender := &pairSender{stream: guarded, progressEvery: uint64(in.ProgressEvery), started: time.Now(), codec: in.ValueCodec, counters: newBucketCounters(bucketName)}
+	if in.ResumeTokens && !isDupsort {
+		sender.tokens, sender.position = s.tokens, resumeToken{bucket: bucketName, prefix: prefix, reverse: reverse}
+	}
+	if span != nil {
+		defer func() { span.SetTag("keys", sender.keys) }()
+	}
+	// each reopen repositions the cursor from the root of the tree, LMDB cursors can't keep position across transactions
+	var reopens int
+	defer func() {
+		if reopens > 0 {
+			s.log.Debug("Seek read transaction was reopened", "bucket", bucketName, "reopens", reopens, "keys", sender.keys)
+		}
+	}()
+
+	// in credit mode client paces the stream by granting credits: one credit is one pair
+	creditMode := !in.StartStreaming && in.Credits > 0
+	credits := uint64(in.Credits)
+
 	// send all items to client, if k==nil - still send it to client and break loop
 	for {
-		err = stream.Send(&remote.Pair{Key: common.CopyBytes(k), Value: common.CopyBytes(v)})
+		if k != nil && len(endKey) > 0 && bytes.Compare(k, endKey) >= 0 { // range end is reached, client gets it as end of data
+			k, v = nil, nil
+		}
+		var batchSize uint32
+		if in.StartStreaming || creditMode { // batching makes sense only when client doesn't drive each step
+			batchSize = in.BatchSize
+		}
+		err = sender.send(k, v, batchSize)
 		if err != nil {
 			return err
 		}
@@ -142,9 +831,27 @@ func (s *KvServer) Seek(stream remote.KV_SeekServer) error {
 			return nil
 		}
 
-		// if client not requested stream then wait signal from him before send any item
-		if !in.StartSreaming {
-			in, err = stream.Recv()
+		if creditMode {
+			credits--
+			for credits == 0 {
+				if err = sender.flush(); err != nil { // client may wait for batched pairs before granting more
+					return err
+				}
+				grant, err := guarded.Recv()
+				if err != nil {
+					if err == io.EOF {
+						return nil
+					}
+					return err
+				}
+				credits += uint64(grant.Credits)
+			}
+			k, v, err = next()
+			if err != nil {
+				return err
+			}
+		} else if !in.StartStreaming { // if client not requested stream then wait signal from him before send any item
+			in, err = guarded.Recv()
 			if err != nil {
 				if err == io.EOF {
 					return nil
@@ -153,6 +860,12 @@ func (s *KvServer) Seek(stream remote.KV_SeekServer) error {
 			}
 
 			if len(in.SeekValue) > 0 {
+				if !isDupsort {
+					return errNotDupSort
+				}
+				if reverse {
+					return errReverseDupSort
+				}
 				k, v, err = c.(ethdb.CursorDupSort).SeekBothRange(in.SeekKey, in.SeekValue)
 				if err != nil {
 					return err
@@ -164,28 +877,42 @@ func (s *KvServer) Seek(stream remote.KV_SeekServer) error {
 					}
 				}
 			} else if len(in.SeekKey) > 0 {
-				k, v, err = c.Seek(in.SeekKey)
+				k, v, err = seek(in.SeekKey)
 				if err != nil {
 					return err
 				}
 			} else {
-				k, v, err = c.Next()
+				k, v, err = next()
 				if err != nil {
 					return err
 				}
 			}
 		} else {
-			k, v, err = c.Next()
+			k, v, err = next()
 			if err != nil {
 				return err
 			}
 		}
 
-		//TODO: protect against client - which doesn't send any requests
+		select {
+		default:
+		case <-stream.Context().Done(): // client is gone - release read transaction right away
+			return stream.Context().Err()
+		}
+
+		if k == nil { // end of data - nothing to re-seek, next iteration will send it to client
+			continue
+		}
+
 		select {
 		default:
 		case <-txTicker.C:
+			if consistent {
+				return status.Errorf(codes.Aborted, "consistent seek didn't finish within %s, read transaction can't be kept longer", s.txTTL)
+			}
 			tx.Rollback()
+			txTimer.reopened()
+			reopens++
 			tx, err = s.kv.Begin(stream.Context(), nil, false)
 			if err != nil {
 				return err
@@ -204,8 +931,8 @@ func (s *KvServer) Seek(stream remote.KV_SeekServer) error {
 				}
 				c = dc
 			} else {
-				c = tx.Cursor(bucketName).Prefix(prefix)
-				k, v, err = c.Seek(k)
+				c = newSeekCursor(tx, bucketName, prefix, reverse)
+				k, v, err = seek(k)
 				if err != nil {
 					return err
 				}
@@ -213,3 +940,290 @@ func (s *KvServer) Seek(stream remote.KV_SeekServer) error {
 		}
 	}
 }
+
+// resumeAfter - key after which stream must continue, taken from signed token if client sent it
+func (s *KvServer) resumeAfter(in *remote.SeekRequest) ([]byte, error) {
+	if len(in.ResumeToken) == 0 {
+		return in.ResumeAfter, nil
+	}
+	if len(in.ResumeAfter) > 0 {
+		return nil, fmt.Errorf("%w: resumeAfter must not be used together with it", errInvalidResumeToken)
+	}
+	token, err := s.tokens.verify(in.ResumeToken)
+	if err != nil {
+		return nil, err
+	}
+	if token.bucket != in.BucketName || !bytes.Equal(token.prefix, in.Prefix) || token.reverse != in.Reverse {
+		return nil, fmt.Errorf("%w: issued for other bucket, prefix or direction", errInvalidResumeToken)
+	}
+	return token.lastKey, nil
+}
+
+var (
+	errNoSeekRequest  = errors.New("stream closed before seek request")
+	errNotDupSort     = errors.New("seekValue is supported only for DupSort seek, it must be set in the first request")
+	errReverseDupSort = errors.New("reverse iteration is not supported for DupSort seek")
+	errResumeDupSort  = errors.New("resumeAfter is not supported for DupSort seek")
+	errReverseEndKey  = errors.New("endKey is not supported for reverse seek")
+)
+
+// seekStatus - gRPC reports plain errors as codes.Unknown, then client can't tell
+// own mistakes and disconnects from failures of the server
+func seekStatus(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled), errors.Is(err, context.Canceled): // any error after disconnect is caused by it
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(ctx.Err(), context.DeadlineExceeded), errors.Is(err, context.DeadlineExceeded), errors.Is(err, errStaleClient):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, errNoSeekRequest), errors.Is(err, errNotDupSort), errors.Is(err, errReverseDupSort), errors.Is(err, errResumeDupSort),
+		errors.Is(err, errInvalidResumeToken), errors.Is(err, errReverseEndKey):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if _, ok := status.FromError(err); ok { // already classified
+		return err
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// newSeekCursor - Prefix cursors don't support Last, so in reverse mode prefix is checked by seekReverse and prevWithPrefix
+func newSeekCursor(tx ethdb.Tx, bucketName string, prefix []byte, reverse bool) ethdb.Cursor {
+	if reverse {
+		return tx.Cursor(bucketName)
+	}
+	return tx.Cursor(bucketName).Prefix(prefix)
+}
+
+// seekReverse - positions cursor on the last key <= seek, or on the last key with given prefix if seek is empty
+func seekReverse(c ethdb.Cursor, prefix []byte, seek []byte) (k, v []byte, err error) {
+	if len(seek) == 0 && len(prefix) > 0 {
+		var ok bool
+		seek, ok = dbutils.NextSubtree(prefix)
+		if ok {
+			if k, v, err = c.Seek(seek); err != nil {
+				return []byte{}, nil, err
+			}
+			if k == nil {
+				k, v, err = c.Last()
+			} else {
+				k, v, err = c.Prev() // seek is after all keys with prefix
+			}
+			return filterPrefix(prefix, k, v, err)
+		}
+		seek = nil // prefix is 0xFF..FF - all keys with prefix are at the end of bucket
+	}
+	if len(seek) == 0 {
+		k, v, err = c.Last()
+		return filterPrefix(prefix, k, v, err)
+	}
+
+	if k, v, err = c.Seek(seek); err != nil {
+		return []byte{}, nil, err
+	}
+	if k == nil {
+		k, v, err = c.Last()
+	} else if !bytes.Equal(k, seek) {
+		k, v, err = c.Prev()
+	}
+	return filterPrefix(prefix, k, v, err)
+}
+
+func prevWithPrefix(c ethdb.Cursor, prefix []byte) (k, v []byte, err error) {
+	k, v, err = c.Prev()
+	return filterPrefix(prefix, k, v, err)
+}
+
+func filterPrefix(prefix []byte, k, v []byte, err error) ([]byte, []byte, error) {
+	if err != nil {
+		return []byte{}, nil, err
+	}
+	if k != nil && !bytes.HasPrefix(k, prefix) {
+		return nil, nil, nil
+	}
+	return k, v, nil
+}
+
+// SeekBatchBytesLimit - Seek flushes a batch when its keys and values reach this size,
+// to keep messages far below gRPC's message size limits
+const SeekBatchBytesLimit = 256 * 1024
+
+// SeekValueChunkSize - Seek splits bigger values into several messages (see Pair.continued),
+// because clients limit size of received messages (gRPC default is 4MB, RemoteKV uses 5MB)
+const SeekValueChunkSize = 1024 * 1024
+
+// pairSender - sends pairs to the Seek stream, packing them into batches
+// and interleaving progress messages if client asked for it
+type pairSender struct {
+	stream     interface{ Send(*remote.Pair) error }
+	batch      []*remote.Pair
+	batchBytes int
+
+	progressEvery uint64
+	started       time.Time
+	keys, bytes   uint64
+
+	codec    remote.ValueCodec
+	counters *bucketCounters
+
+	tokens   *tokenSigner // nil if client didn't ask for resume tokens
+	position resumeToken
+}
+
+// compressMinSize - smaller values are sent as is, compression doesn't pay off for them
+const compressMinSize = 64
+
+// pair - compresses value if client supports it. Pair may reference cursor's memory only if it's sent
+// before the cursor moves: gRPC marshals message before Send returns (or blocks on flow control),
+// batched pairs must be copied.
+func (s *pairSender) pair(k, v []byte, copyKV bool) *remote.Pair {
+	if s.codec == remote.ValueCodec_SNAPPY && len(v) >= compressMinSize {
+		if compressed := snappy.Encode(nil, v); len(compressed) < len(v) {
+			if copyKV {
+				k = common.CopyBytes(k)
+			}
+			return &remote.Pair{Key: k, Value: compressed, ValueCodec: remote.ValueCodec_SNAPPY}
+		}
+	}
+	if copyKV {
+		return &remote.Pair{Key: common.CopyBytes(k), Value: common.CopyBytes(v)}
+	}
+	return &remote.Pair{Key: k, Value: v}
+}
+
+func (s *pairSender) send(k, v []byte, batchSize uint32) error {
+	if batchSize <= 1 || k == nil || len(v) > SeekValueChunkSize {
+		if err := s.flush(); err != nil {
+			return err
+		}
+		pair := s.pair(k, v, false)
+		if k != nil {
+			pair.ResumeToken = s.resumeToken(k)
+		}
+		if err := s.sendChunked(pair); err != nil {
+			return err
+		}
+	} else {
+		s.batch = append(s.batch, s.pair(k, v, true))
+		s.batchBytes += len(k) + len(v)
+		if len(s.batch) >= int(batchSize) || s.batchBytes >= SeekBatchBytesLimit {
+			if err := s.flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if k == nil {
+		return nil
+	}
+
+	s.keys++
+	s.bytes += uint64(len(k) + len(v))
+	s.counters.add(k, v)
+	if s.progressEvery == 0 || s.keys%s.progressEvery != 0 {
+		return nil
+	}
+	if err := s.flush(); err != nil { // progress must not overtake pairs it counts
+		return err
+	}
+	return s.stream.Send(&remote.Pair{Progress: &remote.SeekProgress{
+		Keys:      s.keys,
+		Bytes:     s.bytes,
+		ElapsedMs: uint64(time.Since(s.started).Milliseconds()),
+	}})
+}
+
+// sendChunked - sends value in several messages if it's too big for one
+func (s *pairSender) sendChunked(pair *remote.Pair) error {
+	for len(pair.Value) > SeekValueChunkSize {
+		chunk := &remote.Pair{Key: pair.Key, Value: pair.Value[:SeekValueChunkSize], ValueCodec: pair.ValueCodec, Continued: true}
+		if err := s.stream.Send(chunk); err != nil {
+			return err
+		}
+		pair.Value = pair.Value[SeekValueChunkSize:]
+	}
+	return s.stream.Send(pair)
+}
+
+// resumeToken - client can continue stream after lastKey by this token
+func (s *pairSender) resumeToken(lastKey []byte) []byte {
+	if s.tokens == nil {
+		return nil
+	}
+	s.position.lastKey = lastKey
+	return s.tokens.sign(s.position)
+}
+
+func (s *pairSender) flush() error {
+	if len(s.batch) == 0 {
+		return nil
+	}
+	err := s.stream.Send(&remote.Pair{Batch: s.batch, ResumeToken: s.resumeToken(s.batch[len(s.batch)-1].Key)})
+	s.batch, s.batchBytes = nil, 0
+	return err
+}
+
+// staleClientGuard - performs Send of Seek stream in a separate goroutine, with a deadline.
+// Send blocks while client doesn't drain the stream, then Seek would keep holding a read transaction.
+// After the deadline Seek returns errStaleClient and releases the transaction, returning from the handler
+// cancels the stream and unblocks the pending call. Recv isn't guarded: clients keep their cursors open
+// between requests for as long as they need.
+type staleClientGuard struct {
+	stream  remote.KV_SeekServer
+	timeout time.Duration
+	ops     chan func() error
+	results chan error
+	timer   *time.Timer
+}
+
+func newStaleClientGuard(stream remote.KV_SeekServer, timeout time.Duration) *staleClientGuard {
+	g := &staleClientGuard{stream: stream, timeout: timeout}
+	if timeout <= 0 {
+		return g
+	}
+	g.ops = make(chan func() error)
+	g.results = make(chan error, 1)
+	g.timer = time.NewTimer(timeout)
+	g.timer.Stop()
+	go func() {
+		for op := range g.ops {
+			g.results <- op()
+		}
+	}()
+	return g
+}
+
+func (g *staleClientGuard) do(op func() error) error {
+	if g.timeout <= 0 {
+		return op()
+	}
+	g.ops <- op
+	g.timer.Reset(g.timeout)
+	select {
+	case err := <-g.results:
+		if !g.timer.Stop() {
+			select { // drain fired timer, to not fail next operation
+			case <-g.timer.C:
+			default:
+			}
+		}
+		return err
+	case <-g.timer.C:
+		return errStaleClient
+	}
+}
+
+func (g *staleClientGuard) Send(pair *remote.Pair) error {
+	return g.do(func() error { return g.stream.Send(pair) })
+}
+
+func (g *staleClientGuard) Recv() (*remote.SeekRequest, error) {
+	return g.stream.Recv()
+}
+
+// close - stops the goroutine after its pending operation (if any) returns
+func (g *staleClientGuard) close() {
+	if g.ops != nil {
+		close(g.ops)
+	}
+}
@@ -0,0 +1,1698 @@
+package remotedbserver
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ledgerwatch/turbo-geth/common"
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/eth/stagedsync/stages"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+	"github.com/ledgerwatch/turbo-geth/ethdb/remote"
+	"github.com/ledgerwatch/turbo-geth/log"
+)
+
+// freeAddr returns a local address which was free at the moment of the call.
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+	return addr
+}
+
+func TestStartGrpcGracefulStop(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	require.NoError(t, kv.Update(context.Background(), func(tx ethdb.Tx) error {
+		return tx.Cursor(dbutils.PlainStateBucket).Put([]byte{1}, []byte{2})
+	}))
+
+	addr := freeAddr(t)
+	grpcServer, err := StartGrpc(kv, nil, DefaultGrpcConfig(addr))
+	require.NoError(t, err)
+
+	remoteKV, _, err := ethdb.NewRemote().Path(addr).Open("", "", "")
+	require.NoError(t, err)
+	require.NoError(t, remoteKV.View(context.Background(), func(tx ethdb.Tx) error {
+		v, err := tx.Get(dbutils.PlainStateBucket, []byte{1})
+		if err != nil {
+			return err
+		}
+		require.Equal(t, []byte{2}, v)
+		return nil
+	}))
+	remoteKV.Close()
+
+	grpcServer.GracefulStop()
+
+	_, err = net.Dial("tcp", addr)
+	require.Error(t, err, "listener must be closed after GracefulStop")
+}
+
+// recordingLogger returns a logger which keeps its records, and a func returning records kept so far
+func recordingLogger() (log.Logger, func() []*log.Record) {
+	var mu sync.Mutex
+	var records []*log.Record
+	logger := log.New()
+	logger.SetHandler(log.FuncHandler(func(r *log.Record) error {
+		mu.Lock()
+		defer mu.Unlock()
+		records = append(records, r)
+		return nil
+	}))
+	return logger, func() []*log.Record {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*log.Record(nil), records...)
+	}
+}
+
+func TestStartGrpcLogger(t *testing.T) {
+	db := ethdb.NewLMDB().InMem().MustOpen()
+	defer db.Close()
+	kv := &unavailableKV{KV: db, unavailable: 1}
+	logger, records := recordingLogger()
+
+	addr := freeAddr(t)
+	cfg := DefaultGrpcConfig(addr)
+	cfg.Logger = logger
+	grpcServer, err := StartGrpc(kv, nil, cfg)
+	require.NoError(t, err)
+	defer grpcServer.Stop()
+
+	clientConn, err := grpc.Dial(addr, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer clientConn.Close()
+	stream, err := remote.NewKVClient(clientConn).Seek(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix}))
+	_, err = stream.Recv()
+	require.Equal(t, codes.Internal, status.Code(err))
+
+	var msgs []string
+	for _, r := range records() {
+		msgs = append(msgs, r.Msg)
+		require.Equal(t, []interface{}{"private_api", addr}, r.Ctx[:2], r.Msg)
+	}
+	require.Equal(t, []string{"Starting private RPC server", "Seek failed"}, msgs)
+}
+
+func TestStartGrpcListenError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	_, err = StartGrpc(nil, nil, DefaultGrpcConfig(l.Addr().String()))
+	require.Error(t, err)
+}
+
+// serveInMem serves kvSrv over an in-memory listener
+func serveInMem(t testing.TB, kvSrv *KvServer) *bufconn.Listener {
+	conn := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	remote.RegisterKVService(grpcServer, remote.NewKVService(kvSrv))
+	remote.RegisterMutableKVService(grpcServer, remote.NewMutableKVService(kvSrv))
+	go func() {
+		_ = grpcServer.Serve(conn)
+	}()
+	t.Cleanup(func() {
+		grpcServer.Stop()
+		_ = conn.Close()
+	})
+	return conn
+}
+
+// startInMemServer serves kvSrv over an in-memory listener and returns a client connected to it.
+func startInMemServer(t *testing.T, kvSrv *KvServer) ethdb.KV {
+	remoteKV, _ := ethdb.NewRemote().InMem(serveInMem(t, kvSrv)).MustOpen()
+	t.Cleanup(remoteKV.Close)
+	return remoteKV
+}
+
+// dialInMem returns raw KV client, to test protocol details hidden by ethdb.RemoteKV
+func dialInMem(t testing.TB, conn *bufconn.Listener) remote.KVClient {
+	return remote.NewKVClient(dialConnInMem(t, conn))
+}
+
+func dialConnInMem(t testing.TB, conn *bufconn.Listener) *grpc.ClientConn {
+	clientConn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, url string) (net.Conn, error) {
+		return conn.Dial()
+	}))
+	require.NoError(t, err)
+	t.Cleanup(func() { clientConn.Close() })
+	return clientConn
+}
+
+// txCountingKV - counts read-only transactions which are not rolled back yet
+type txCountingKV struct {
+	ethdb.KV
+	open    int32
+	maxOpen int32
+	begun   int32
+}
+
+type txCounting struct {
+	ethdb.Tx
+	kv *txCountingKV
+}
+
+func (kv *txCountingKV) Begin(ctx context.Context, parent ethdb.Tx, writable bool) (ethdb.Tx, error) {
+	tx, err := kv.KV.Begin(ctx, parent, writable)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt32(&kv.begun, 1)
+	open := atomic.AddInt32(&kv.open, 1)
+	for max := atomic.LoadInt32(&kv.maxOpen); open > max; max = atomic.LoadInt32(&kv.maxOpen) {
+		if atomic.CompareAndSwapInt32(&kv.maxOpen, max, open) {
+			break
+		}
+	}
+	return &txCounting{Tx: tx, kv: kv}, nil
+}
+
+func (tx *txCounting) Rollback() {
+	atomic.AddInt32(&tx.kv.open, -1)
+	tx.Tx.Rollback()
+}
+
+func (kv *txCountingKV) openTxs() int32    { return atomic.LoadInt32(&kv.open) }
+func (kv *txCountingKV) maxOpenTxs() int32 { return atomic.LoadInt32(&kv.maxOpen) }
+
+// writeSequence puts keys 0..n-1 (4 bytes big-endian, value equal to key) into bucket
+func writeSequence(t *testing.T, kv ethdb.KV, bucket string, n uint32) {
+	require.NoError(t, kv.Update(context.Background(), func(tx ethdb.Tx) error {
+		c := tx.Cursor(bucket)
+		for i := uint32(0); i < n; i++ {
+			k := make([]byte, 4)
+			binary.BigEndian.PutUint32(k, i)
+			if err := c.Put(k, k); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+func TestSeekReopensTxAfterTTL(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+
+	const n = 1000
+	writeSequence(t, kv, dbutils.BlockBodyPrefix, n)
+
+	remoteKV := startInMemServer(t, NewKvServer(kv, time.Nanosecond))
+	require.NoError(t, remoteKV.View(context.Background(), func(tx ethdb.Tx) error {
+		c := tx.Cursor(dbutils.BlockBodyPrefix)
+		i := uint32(0)
+		for k, v, err := c.First(); k != nil; k, v, err = c.Next() {
+			require.NoError(t, err)
+			require.Equal(t, i, binary.BigEndian.Uint32(k))
+			require.Equal(t, k, v)
+			i++
+		}
+		require.Equal(t, uint32(n), i)
+		return nil
+	}))
+}
+
+func TestSeekBatching(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	const n = 1000
+	writeSequence(t, kv, dbutils.BlockBodyPrefix, n)
+
+	conn := serveInMem(t, NewKvServer(kv, MaxTxTTL))
+
+	t.Run("client", func(t *testing.T) {
+		remoteKV, _ := ethdb.NewRemote().InMem(conn).MustOpen()
+		defer remoteKV.Close()
+		require.NoError(t, remoteKV.View(context.Background(), func(tx ethdb.Tx) error {
+			c := tx.Cursor(dbutils.BlockBodyPrefix).Prefetch(100)
+			i := uint32(0)
+			for k, v, err := c.First(); k != nil; k, v, err = c.Next() {
+				require.NoError(t, err)
+				require.Equal(t, i, binary.BigEndian.Uint32(k))
+				require.Equal(t, k, v)
+				i++
+			}
+			require.Equal(t, uint32(n), i)
+			return nil
+		}))
+	})
+
+	t.Run("messages", func(t *testing.T) {
+		stream, err := dialInMem(t, conn).Seek(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix}))
+		pair, err := stream.Recv() // first pair is always sent alone
+		require.NoError(t, err)
+		require.Equal(t, uint32(0), binary.BigEndian.Uint32(pair.Key))
+
+		require.NoError(t, stream.Send(&remote.SeekRequest{StartStreaming: true, BatchSize: 100}))
+		var messages, pairs int
+		for {
+			pair, err = stream.Recv()
+			require.NoError(t, err)
+			if len(pair.Batch) == 0 {
+				require.Nil(t, pair.Key)
+				break
+			}
+			messages++
+			pairs += len(pair.Batch)
+		}
+		require.Equal(t, n-1, pairs)
+		require.Equal(t, 10, messages)
+	})
+}
+
+// marshalingStream - like gRPC, marshals message before Send returns, then pair's memory can be reused
+type marshalingStream struct{ size int }
+
+func (s *marshalingStream) Send(pair *remote.Pair) error {
+	b, err := proto.Marshal(pair)
+	s.size += len(b)
+	return err
+}
+
+// BenchmarkPairSender - allocations of Seek sending a large scan: not batched pairs are sent without copying,
+// batched pairs are copied because cursor moves before they are sent
+func BenchmarkPairSender(b *testing.B) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	require.NoError(b, kv.Update(context.Background(), func(tx ethdb.Tx) error {
+		c := tx.Cursor(dbutils.BlockBodyPrefix)
+		for i := uint32(0); i < 100_000; i++ {
+			k := make([]byte, 4)
+			binary.BigEndian.PutUint32(k, i)
+			if err := c.Put(k, make([]byte, 256)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	for _, batchSize := range []uint32{1, 100} {
+		b.Run(fmt.Sprintf("batch=%d", batchSize), func(b *testing.B) {
+			b.ReportAllocs()
+			require.NoError(b, kv.View(context.Background(), func(tx ethdb.Tx) error {
+				for i := 0; i < b.N; i++ {
+					sender := &pairSender{stream: &marshalingStream{}}
+					c := tx.Cursor(dbutils.BlockBodyPrefix)
+					for k, v, err := c.First(); ; k, v, err = c.Next() {
+						require.NoError(b, err)
+						require.NoError(b, sender.send(k, v, batchSize))
+						if k == nil {
+							break
+						}
+					}
+				}
+				return nil
+			}))
+		})
+	}
+}
+
+func TestSeekLargeValue(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	large := make([]byte, 6*1024*1024) // bigger than max message size of RemoteKV client
+	_, _ = rand.Read(large)
+	values := [][]byte{{1}, large, {3}}
+	require.NoError(t, kv.Update(context.Background(), func(tx ethdb.Tx) error {
+		for i, v := range values {
+			if err := tx.Cursor(dbutils.BlockBodyPrefix).Put([]byte{byte(i)}, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	conn := serveInMem(t, NewKvServer(kv, MaxTxTTL))
+
+	for _, codec := range []remote.ValueCodec{remote.ValueCodec_NONE, remote.ValueCodec_SNAPPY} {
+		remoteKV, _, err := ethdb.NewRemote().InMem(conn).WithValueCodec(codec).Open("", "", "")
+		require.NoError(t, err)
+		for _, prefetch := range []uint{0, 10} {
+			require.NoError(t, remoteKV.View(context.Background(), func(tx ethdb.Tx) error {
+				var actual [][]byte
+				c := tx.Cursor(dbutils.BlockBodyPrefix).Prefetch(prefetch)
+				for k, v, err := c.First(); k != nil; k, v, err = c.Next() {
+					require.NoError(t, err)
+					actual = append(actual, v)
+				}
+				require.Equal(t, values, actual, "codec %s, prefetch %d", codec, prefetch)
+				return nil
+			}))
+		}
+		remoteKV.Close()
+	}
+
+	// large value is split into chunks which fit into messages
+	stream, err := dialInMem(t, conn).Seek(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, SeekKey: []byte{1}}))
+	var chunks int
+	for {
+		pair, err := stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, []byte{1}, pair.Key)
+		require.LessOrEqual(t, len(pair.Value), SeekValueChunkSize)
+		chunks++
+		if !pair.Continued {
+			break
+		}
+	}
+	require.Equal(t, 6, chunks)
+	require.NoError(t, stream.CloseSend())
+}
+
+func TestGetWithoutSeekExact(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	writeSequence(t, kv, dbutils.BlockBodyPrefix, 10)
+
+	// server built before SeekExact RPC was added
+	conn := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	remote.RegisterKVService(grpcServer, &remote.KVService{Seek: NewKvServer(kv, MaxTxTTL).Seek})
+	go func() {
+		_ = grpcServer.Serve(conn)
+	}()
+	defer grpcServer.Stop()
+	remoteKV, _ := ethdb.NewRemote().InMem(conn).MustOpen()
+	defer remoteKV.Close()
+
+	for i := 0; i < 2; i++ { // the second Get doesn't try SeekExact anymore
+		require.NoError(t, remoteKV.View(context.Background(), func(tx ethdb.Tx) error {
+			v, err := tx.Get(dbutils.BlockBodyPrefix, []byte{0, 0, 0, 3})
+			require.NoError(t, err)
+			require.NotNil(t, v)
+			v, err = tx.Get(dbutils.BlockBodyPrefix, []byte{0, 0, 0, 10})
+			require.NoError(t, err)
+			require.Nil(t, v)
+			return nil
+		}))
+	}
+}
+
+func TestSeekExact(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	writeSequence(t, kv, dbutils.BlockBodyPrefix, 10)
+	require.NoError(t, kv.Update(context.Background(), func(tx ethdb.Tx) error {
+		return tx.Cursor(dbutils.PlainStateBucket).Put([]byte{1}, []byte{2})
+	}))
+
+	remoteKV := startInMemServer(t, NewKvServer(kv, MaxTxTTL))
+
+	cases := []struct {
+		bucket string
+		key    []byte
+	}{
+		{dbutils.BlockBodyPrefix, []byte{0, 0, 0, 0}},
+		{dbutils.BlockBodyPrefix, []byte{0, 0, 0, 9}},
+		{dbutils.BlockBodyPrefix, []byte{0, 0, 0, 10}}, // missing
+		{dbutils.BlockBodyPrefix, []byte{0, 0}},        // missing, but prefix of existing keys
+		{dbutils.PlainStateBucket, []byte{1}},
+		{dbutils.PlainStateBucket, []byte{2}}, // missing
+	}
+	for _, tc := range cases {
+		var expected, actual []byte
+		require.NoError(t, kv.View(context.Background(), func(tx ethdb.Tx) (err error) {
+			expected, err = tx.Get(tc.bucket, tc.key)
+			return err
+		}))
+		require.NoError(t, remoteKV.View(context.Background(), func(tx ethdb.Tx) (err error) {
+			actual, err = tx.Get(tc.bucket, tc.key)
+			return err
+		}))
+		require.Equal(t, expected, actual, "bucket %s, key %x", tc.bucket, tc.key)
+	}
+}
+
+func TestMultiSeekExact(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	client := dialInMem(t, serveInMem(t, NewKvServer(kv, MaxTxTTL)))
+
+	buckets := []string{dbutils.HeaderPrefix, dbutils.BlockBodyPrefix, dbutils.BlockReceiptsPrefix}
+	key := []byte{1}
+	write := func(i uint32) error {
+		return kv.Update(context.Background(), func(tx ethdb.Tx) error {
+			v := make([]byte, 4)
+			binary.BigEndian.PutUint32(v, i)
+			for _, name := range buckets {
+				if err := tx.Cursor(name).Put(key, v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	require.NoError(t, write(0))
+
+	// writer updates all buckets in one transaction, then reader must never see them different
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	writerDone := make(chan error, 1)
+	go func() {
+		for i := uint32(1); ctx.Err() == nil; i++ {
+			if err := write(i); err != nil {
+				writerDone <- err
+				return
+			}
+		}
+		writerDone <- nil
+	}()
+	for i := 0; i < 200; i++ {
+		reply, err := client.MultiSeekExact(ctx, &remote.MultiSeekExactRequest{BucketNames: buckets, Key: key})
+		require.NoError(t, err)
+		require.Len(t, reply.Values, len(buckets))
+		for _, value := range reply.Values {
+			require.True(t, value.Found)
+			require.Equal(t, reply.Values[0].Value, value.Value)
+		}
+	}
+	cancel()
+	require.NoError(t, <-writerDone)
+
+	reply, err := client.MultiSeekExact(context.Background(), &remote.MultiSeekExactRequest{BucketNames: buckets, Key: []byte{2}})
+	require.NoError(t, err)
+	for _, value := range reply.Values {
+		require.False(t, value.Found)
+	}
+	_, err = client.MultiSeekExact(context.Background(), &remote.MultiSeekExactRequest{BucketNames: []string{dbutils.HeaderPrefix, "unknown"}, Key: key})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestMutableKV(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	writeSequence(t, kv, dbutils.BlockBodyPrefix, 10)
+	get := func(key []byte) (v []byte) {
+		require.NoError(t, kv.View(context.Background(), func(tx ethdb.Tx) (err error) {
+			v, err = tx.Get(dbutils.BlockBodyPrefix, key)
+			return err
+		}))
+		return v
+	}
+
+	t.Run("read-only", func(t *testing.T) {
+		client := remote.NewMutableKVClient(dialConnInMem(t, serveInMem(t, NewKvServer(kv, MaxTxTTL))))
+		_, err := client.Put(context.Background(), &remote.PutRequest{BucketName: dbutils.BlockBodyPrefix, Key: []byte{1}, Value: []byte{2}})
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+		_, err = client.Delete(context.Background(), &remote.DeleteRequest{BucketName: dbutils.BlockBodyPrefix, Key: []byte{0, 0, 0, 1}})
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+		require.Nil(t, get([]byte{1}))
+		require.Equal(t, []byte{0, 0, 0, 1}, get([]byte{0, 0, 0, 1}))
+	})
+
+	t.Run("writable", func(t *testing.T) {
+		client := remote.NewMutableKVClient(dialConnInMem(t, serveInMem(t, NewKvServerRW(kv, MaxTxTTL))))
+		_, err := client.Put(context.Background(), &remote.PutRequest{BucketName: dbutils.BlockBodyPrefix, Key: []byte{1}, Value: []byte{2}})
+		require.NoError(t, err)
+		require.Equal(t, []byte{2}, get([]byte{1}))
+		_, err = client.Delete(context.Background(), &remote.DeleteRequest{BucketName: dbutils.BlockBodyPrefix, Key: []byte{0, 0, 0, 1}})
+		require.NoError(t, err)
+		require.Nil(t, get([]byte{0, 0, 0, 1}))
+
+		_, err = client.Put(context.Background(), &remote.PutRequest{BucketName: "unknown", Key: []byte{1}, Value: []byte{2}})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestSeekReverse(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	writeSequence(t, kv, dbutils.BlockBodyPrefix, 600)
+
+	client := dialInMem(t, serveInMem(t, NewKvServer(kv, MaxTxTTL)))
+
+	// scan - returns keys sent by server, first key requested by Seek, others by Next (streaming or step by step)
+	scan := func(req *remote.SeekRequest, streaming bool) (keys []uint32) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		stream, err := client.Seek(ctx)
+		require.NoError(t, err)
+		req.BucketName = dbutils.BlockBodyPrefix
+		require.NoError(t, stream.Send(req))
+		if streaming {
+			require.NoError(t, stream.Send(&remote.SeekRequest{StartStreaming: true}))
+		}
+		for {
+			pair, err := stream.Recv()
+			require.NoError(t, err)
+			if pair.Key == nil {
+				return keys
+			}
+			keys = append(keys, binary.BigEndian.Uint32(pair.Key))
+			if !streaming {
+				require.NoError(t, stream.Send(&remote.SeekRequest{}))
+			}
+		}
+	}
+	sequence := func(from, to uint32) (keys []uint32) {
+		for i := from; ; {
+			keys = append(keys, i)
+			if i == to {
+				return keys
+			}
+			if from < to {
+				i++
+			} else {
+				i--
+			}
+		}
+	}
+
+	cases := []struct {
+		name     string
+		req      *remote.SeekRequest
+		expected []uint32
+	}{
+		{"forward", &remote.SeekRequest{}, sequence(0, 599)},
+		{"forward with prefix", &remote.SeekRequest{Prefix: []byte{0, 0, 1}, SeekKey: []byte{0, 0, 1}}, sequence(256, 511)},
+		{"reverse", &remote.SeekRequest{Reverse: true}, sequence(599, 0)},
+		{"reverse from existing key", &remote.SeekRequest{Reverse: true, SeekKey: []byte{0, 0, 1, 44}}, sequence(300, 0)},
+		{"reverse from missing key", &remote.SeekRequest{Reverse: true, SeekKey: []byte{0, 0, 1, 44, 1}}, sequence(300, 0)},
+		{"reverse from key after last", &remote.SeekRequest{Reverse: true, SeekKey: []byte{1}}, sequence(599, 0)},
+		{"reverse with prefix", &remote.SeekRequest{Reverse: true, Prefix: []byte{0, 0, 1}}, sequence(511, 256)},
+		{"reverse with prefix at end of bucket", &remote.SeekRequest{Reverse: true, Prefix: []byte{0, 0, 2}}, sequence(599, 512)},
+		{"reverse with prefix and key", &remote.SeekRequest{Reverse: true, Prefix: []byte{0, 0, 1}, SeekKey: []byte{0, 0, 1, 10}}, sequence(266, 256)},
+		{"reverse with missing prefix", &remote.SeekRequest{Reverse: true, Prefix: []byte{0, 0, 3}}, nil},
+	}
+	for _, tc := range cases {
+		for _, streaming := range []bool{false, true} {
+			require.Equal(t, tc.expected, scan(tc.req, streaming), "%s, streaming=%t", tc.name, streaming)
+		}
+	}
+
+	stream, err := client.Seek(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: dbutils.PlainStateBucket, SeekKey: []byte{1}, SeekValue: []byte{1}, Reverse: true}))
+	_, err = stream.Recv()
+	require.Error(t, err, "reverse DupSort seek is not supported")
+}
+
+func TestSeekReleasesTxOnClientCancel(t *testing.T) {
+	db := ethdb.NewLMDB().InMem().MustOpen()
+	defer db.Close()
+	writeSequence(t, db, dbutils.BlockBodyPrefix, 100_000)
+	kv := &txCountingKV{KV: db}
+
+	client := dialInMem(t, serveInMem(t, NewKvServer(kv, MaxTxTTL)))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.Seek(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, StartStreaming: true}))
+	for i := 0; i < 10; i++ {
+		_, err = stream.Recv()
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(1), kv.openTxs())
+
+	cancel()
+	require.Eventually(t, func() bool { return kv.openTxs() == 0 }, time.Second, time.Millisecond)
+}
+
+// slowSeekStream - server side of Seek stream, client reads slowly and has a deadline
+type slowSeekStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	req *remote.SeekRequest
+}
+
+func (s *slowSeekStream) Context() context.Context { return s.ctx }
+func (s *slowSeekStream) Recv() (*remote.SeekRequest, error) {
+	req := s.req
+	s.req = nil
+	if req == nil {
+		<-s.ctx.Done()
+		return nil, s.ctx.Err()
+	}
+	return req, nil
+}
+func (s *slowSeekStream) Send(*remote.Pair) error {
+	time.Sleep(time.Millisecond)
+	return nil
+}
+
+func TestSeekClientDeadline(t *testing.T) {
+	db := ethdb.NewLMDB().InMem().MustOpen()
+	defer db.Close()
+	writeSequence(t, db, dbutils.BlockBodyPrefix, 100_000)
+	kv := &txCountingKV{KV: db}
+	srv := NewKvServer(kv, MaxTxTTL)
+
+	t.Run("long scan", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		err := srv.Seek(&slowSeekStream{ctx: ctx, req: &remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, StartStreaming: true}})
+		require.Equal(t, codes.DeadlineExceeded, status.Code(err), err)
+		require.Equal(t, int32(0), kv.openTxs())
+	})
+
+	t.Run("client doesn't send next request", func(t *testing.T) { // deadline is much shorter than stale client timeout
+		client := dialInMem(t, serveInMem(t, srv))
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		stream, err := client.Seek(ctx)
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix}))
+		_, err = stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, int32(1), kv.openTxs())
+		require.Eventually(t, func() bool { return kv.openTxs() == 0 }, time.Second, time.Millisecond)
+	})
+}
+
+func TestMaxReadTxs(t *testing.T) {
+	db := ethdb.NewLMDB().InMem().MustOpen()
+	defer db.Close()
+	writeSequence(t, db, dbutils.BlockBodyPrefix, 10)
+	kv := &txCountingKV{KV: db}
+
+	const limit = 2
+	client := dialInMem(t, serveInMem(t, NewKvServer(kv, MaxTxTTL).WithMaxReadTxs(limit, 100*time.Millisecond)))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// every stream holds its read transaction until the client closes it
+	openStream := func(ctx context.Context) (remote.KV_SeekClient, error) {
+		stream, err := client.Seek(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err = stream.Send(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix}); err != nil {
+			return nil, err
+		}
+		_, err = stream.Recv()
+		return stream, err
+	}
+
+	streamCtx, closeStream := context.WithCancel(ctx)
+	_, err := openStream(streamCtx)
+	require.NoError(t, err)
+	_, err = openStream(ctx)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = openStream(ctx)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.Equal(t, codes.ResourceExhausted, status.Code(err), err)
+	}
+	_, err = client.SeekExact(ctx, &remote.SeekExactRequest{BucketName: dbutils.BlockBodyPrefix, Key: []byte{0, 0, 0, 1}})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err), err)
+	require.Equal(t, int32(limit), kv.maxOpenTxs())
+
+	// waiting request gets the slot once another stream is closed
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		closeStream()
+	}()
+	_, err = openStream(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int32(limit), kv.maxOpenTxs())
+}
+
+func TestSeekStaleClient(t *testing.T) {
+	db := ethdb.NewLMDB().InMem().MustOpen()
+	defer db.Close()
+	// values are big enough to fill all buffers between server and client
+	require.NoError(t, db.Update(context.Background(), func(tx ethdb.Tx) error {
+		c := tx.Cursor(dbutils.BlockBodyPrefix)
+		for i := uint32(0); i < 10_000; i++ {
+			k := make([]byte, 4)
+			binary.BigEndian.PutUint32(k, i)
+			if err := c.Put(k, make([]byte, 1024)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	kv := &txCountingKV{KV: db}
+	client := dialInMem(t, serveInMem(t, NewKvServer(kv, MaxTxTTL).WithStaleClientTimeout(50*time.Millisecond)))
+
+	t.Run("client doesn't read", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		stream, err := client.Seek(ctx)
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, StartStreaming: true}))
+		_, err = stream.Recv()
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool { return kv.openTxs() == 0 }, 2*time.Second, time.Millisecond)
+		for err == nil { // buffered pairs come first
+			_, err = stream.Recv()
+		}
+		require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	})
+
+	t.Run("idle client keeps its cursor", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		stream, err := client.Seek(ctx)
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix}))
+		_, err = stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, int32(1), kv.openTxs())
+
+		time.Sleep(200 * time.Millisecond) // longer than the stale client timeout
+		require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, SeekKey: []byte{0, 0, 0, 5}}))
+		pair, err := stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, []byte{0, 0, 0, 5}, pair.Key)
+
+		cancel()
+		require.Eventually(t, func() bool { return kv.openTxs() == 0 }, 2*time.Second, time.Millisecond)
+	})
+}
+
+func TestUnknownBucket(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	client := dialInMem(t, serveInMem(t, NewKvServer(kv, MaxTxTTL)))
+
+	stream, err := client.Seek(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: "no_such_bucket"}))
+	_, err = stream.Recv()
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.Contains(t, status.Convert(err).Message(), "no_such_bucket")
+
+	_, err = client.SeekExact(context.Background(), &remote.SeekExactRequest{BucketName: "no_such_bucket", Key: []byte{1}})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestAllowedBuckets(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	writeSequence(t, kv, dbutils.HeaderPrefix, 10)
+	writeSequence(t, kv, dbutils.BlockBodyPrefix, 10)
+	conn := serveInMem(t, NewKvServerRW(kv, MaxTxTTL).WithAllowedBuckets(dbutils.HeaderPrefix))
+	client := dialInMem(t, conn)
+
+	seek := func(bucket string) error {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel() // release read transaction
+		stream, err := client.Seek(ctx)
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: bucket}))
+		_, err = stream.Recv()
+		return err
+	}
+	require.NoError(t, seek(dbutils.HeaderPrefix))
+	require.Equal(t, codes.PermissionDenied, status.Code(seek(dbutils.BlockBodyPrefix)))
+	require.Equal(t, codes.PermissionDenied, status.Code(seek("no_such_bucket"))) // doesn't reveal which buckets exist
+
+	key := []byte{0, 0, 0, 1}
+	_, err := client.SeekExact(context.Background(), &remote.SeekExactRequest{BucketName: dbutils.BlockBodyPrefix, Key: key})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.MultiSeekExact(context.Background(), &remote.MultiSeekExactRequest{BucketNames: []string{dbutils.HeaderPrefix, dbutils.BlockBodyPrefix}, Key: key})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.Count(context.Background(), &remote.CountRequest{BucketName: dbutils.BlockBodyPrefix})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = remote.NewMutableKVClient(dialConnInMem(t, conn)).Delete(context.Background(), &remote.DeleteRequest{BucketName: dbutils.BlockBodyPrefix, Key: key})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	reply, err := client.Count(context.Background(), &remote.CountRequest{BucketName: dbutils.HeaderPrefix})
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), reply.Count)
+}
+
+func TestSeekStatusCodes(t *testing.T) {
+	db := ethdb.NewLMDB().InMem().MustOpen()
+	defer db.Close()
+	writeSequence(t, db, dbutils.BlockBodyPrefix, 10)
+	kv := &unavailableKV{KV: db}
+	client := dialInMem(t, serveInMem(t, NewKvServer(kv, MaxTxTTL).WithStaleClientTimeout(50*time.Millisecond)))
+
+	seek := func(requests ...*remote.SeekRequest) error {
+		stream, err := client.Seek(context.Background())
+		require.NoError(t, err)
+		if len(requests) == 0 {
+			require.NoError(t, stream.CloseSend())
+			_, err = stream.Recv()
+			return err
+		}
+		for _, req := range requests {
+			require.NoError(t, stream.Send(req))
+			if _, err = stream.Recv(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	t.Run("bad requests", func(t *testing.T) {
+		require.Equal(t, codes.InvalidArgument, status.Code(seek()))
+		require.Equal(t, codes.InvalidArgument, status.Code(seek(&remote.SeekRequest{BucketName: dbutils.PlainStateBucket, SeekKey: []byte{1}, SeekValue: []byte{1}, Reverse: true})))
+		require.Equal(t, codes.InvalidArgument, status.Code(seek(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, SeekValue: []byte{1}})))
+		require.Equal(t, codes.InvalidArgument, status.Code(seek(
+			&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix},
+			&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, SeekKey: []byte{1}, SeekValue: []byte{1}},
+		)))
+		require.Equal(t, codes.NotFound, status.Code(seek(&remote.SeekRequest{BucketName: "unknown"})))
+	})
+
+	t.Run("db failure", func(t *testing.T) {
+		atomic.StoreInt32(&kv.unavailable, 1)
+		defer atomic.StoreInt32(&kv.unavailable, 0)
+		require.Equal(t, codes.Internal, status.Code(seek(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix})))
+	})
+
+	t.Run("client disconnect", func(t *testing.T) { // client can't see it, check classification directly
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.Equal(t, codes.Canceled, status.Code(seekStatus(ctx, errors.New("transport is closing"))))
+		require.Equal(t, codes.Canceled, status.Code(seekStatus(context.Background(), ctx.Err())))
+		require.NoError(t, seekStatus(ctx, nil))
+	})
+}
+
+func TestGrpcConfigMaxConcurrentStreams(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	writeSequence(t, kv, dbutils.BlockBodyPrefix, 10)
+
+	// openStreams - opens n Seek streams on one connection and keeps them open, returns how many got first pair in time
+	openStreams := func(maxStreams uint32, n int) int {
+		addr := freeAddr(t)
+		cfg := DefaultGrpcConfig(addr)
+		cfg.MaxConcurrentStreams = maxStreams
+		grpcServer, err := StartGrpc(kv, nil, cfg)
+		require.NoError(t, err)
+		defer grpcServer.Stop()
+		clientConn, err := grpc.Dial(addr, grpc.WithInsecure())
+		require.NoError(t, err)
+		defer clientConn.Close()
+		client := remote.NewKVClient(clientConn)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		var served int32
+		done := make(chan struct{}, n)
+		for i := 0; i < n; i++ {
+			go func() {
+				defer func() { done <- struct{}{} }()
+				stream, err := client.Seek(ctx)
+				if err != nil {
+					return
+				}
+				if err = stream.Send(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix}); err != nil {
+					return
+				}
+				if _, err = stream.Recv(); err != nil {
+					return
+				}
+				atomic.AddInt32(&served, 1)
+				<-ctx.Done() // keep stream open until all streams tried
+			}()
+		}
+		for i := 0; i < n; i++ {
+			<-done
+		}
+		return int(atomic.LoadInt32(&served))
+	}
+
+	require.Equal(t, 60, openStreams(100, 60))
+	require.Equal(t, 2, openStreams(2, 5))
+}
+
+func TestGrpcConfigValidation(t *testing.T) {
+	cfg := DefaultGrpcConfig(freeAddr(t))
+	cfg.MaxConcurrentStreams = 0
+	_, err := StartGrpc(nil, nil, cfg)
+	require.Error(t, err)
+
+	cfg = DefaultGrpcConfig(freeAddr(t))
+	cfg.ReadBufferSize = -1
+	_, err = StartGrpc(nil, nil, cfg)
+	require.Error(t, err)
+
+	cfg = DefaultGrpcConfig(freeAddr(t))
+	cfg.MaxReadTxs = -1
+	_, err = StartGrpc(nil, nil, cfg)
+	require.Error(t, err)
+}
+
+func TestGrpcRateLimit(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+
+	addr := freeAddr(t)
+	cfg := DefaultGrpcConfig(addr)
+	cfg.RateLimit, cfg.RateBurst = 0.001, 3 // practically no refill during the test
+	grpcServer, err := StartGrpc(kv, nil, cfg)
+	require.NoError(t, err)
+	defer grpcServer.Stop()
+
+	clientConn, err := grpc.Dial(addr, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer clientConn.Close()
+	client := remote.NewKVClient(clientConn)
+
+	for i := 0; i < 3; i++ {
+		_, err = client.SeekExact(context.Background(), &remote.SeekExactRequest{BucketName: dbutils.BlockBodyPrefix, Key: []byte{1}})
+		require.NoError(t, err)
+	}
+	_, err = client.SeekExact(context.Background(), &remote.SeekExactRequest{BucketName: dbutils.BlockBodyPrefix, Key: []byte{1}})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// streams share the limit
+	stream, err := client.Seek(context.Background())
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// new connection from same host doesn't reset the limit
+	clientConn2, err := grpc.Dial(addr, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer clientConn2.Close()
+	_, err = remote.NewKVClient(clientConn2).SeekExact(context.Background(), &remote.SeekExactRequest{BucketName: dbutils.BlockBodyPrefix, Key: []byte{1}})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestSeekResumeAfterDisconnect(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	const n = 1000
+	writeSequence(t, kv, dbutils.BlockBodyPrefix, n)
+	client := dialInMem(t, serveInMem(t, NewKvServer(kv, MaxTxTTL)))
+
+	// read - streams keys until limit reached or end of data
+	read := func(req *remote.SeekRequest, limit int) (keys [][]byte) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel() // for interrupted stream works as disconnect
+		stream, err := client.Seek(ctx)
+		require.NoError(t, err)
+		req.BucketName = dbutils.BlockBodyPrefix
+		req.StartStreaming = true
+		require.NoError(t, stream.Send(req))
+		for len(keys) < limit {
+			pair, err := stream.Recv()
+			require.NoError(t, err)
+			if pair.Key == nil {
+				break
+			}
+			keys = append(keys, pair.Key)
+		}
+		return keys
+	}
+
+	for _, reverse := range []bool{false, true} {
+		keys := read(&remote.SeekRequest{Reverse: reverse}, 300)
+		require.Equal(t, 300, len(keys))
+		keys = append(keys, read(&remote.SeekRequest{Reverse: reverse, ResumeAfter: keys[len(keys)-1]}, n)...)
+
+		require.Equal(t, n, len(keys), "reverse=%t", reverse)
+		for i, k := range keys {
+			expected := uint32(i)
+			if reverse {
+				expected = n - 1 - uint32(i)
+			}
+			require.Equal(t, expected, binary.BigEndian.Uint32(k), "reverse=%t", reverse)
+		}
+	}
+
+	// key which client received was deleted before resume
+	require.NoError(t, kv.Update(context.Background(), func(tx ethdb.Tx) error {
+		return tx.Cursor(dbutils.BlockBodyPrefix).Delete([]byte{0, 0, 0, 10})
+	}))
+	keys := read(&remote.SeekRequest{ResumeAfter: []byte{0, 0, 0, 10}}, 1)
+	require.Equal(t, []byte{0, 0, 0, 11}, keys[0])
+}
+
+func TestSeekResumeToken(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	const n = 1000
+	writeSequence(t, kv, dbutils.HeaderPrefix, n)
+	writeSequence(t, kv, dbutils.BlockBodyPrefix, n)
+	client := dialInMem(t, serveInMem(t, NewKvServer(kv, MaxTxTTL)))
+
+	// read - streams keys (in batches) until limit reached or end of data, returns token of last received message
+	read := func(req *remote.SeekRequest, limit int) (keys [][]byte, token []byte, err error) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel() // for interrupted stream works as disconnect
+		stream, err := client.Seek(ctx)
+		require.NoError(t, err)
+		req.StartStreaming, req.BatchSize, req.ResumeTokens = true, 7, true
+		require.NoError(t, stream.Send(req))
+		for len(keys) < limit {
+			pair, err := stream.Recv()
+			if err != nil {
+				return nil, nil, err
+			}
+			if pair.Key == nil && len(pair.Batch) == 0 {
+				break
+			}
+			require.NotEmpty(t, pair.ResumeToken)
+			token = pair.ResumeToken
+			if len(pair.Batch) == 0 {
+				keys = append(keys, pair.Key)
+			}
+			for _, p := range pair.Batch {
+				keys = append(keys, p.Key)
+			}
+		}
+		return keys, token, nil
+	}
+
+	t.Run("resume", func(t *testing.T) {
+		for _, reverse := range []bool{false, true} {
+			keys, token, err := read(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, Reverse: reverse}, 300)
+			require.NoError(t, err)
+			rest, _, err := read(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, Reverse: reverse, ResumeToken: token}, n)
+			require.NoError(t, err)
+			keys = append(keys, rest...)
+
+			require.Equal(t, n, len(keys), "reverse=%t", reverse)
+			for i, k := range keys {
+				expected := uint32(i)
+				if reverse {
+					expected = n - 1 - uint32(i)
+				}
+				require.Equal(t, expected, binary.BigEndian.Uint32(k), "reverse=%t", reverse)
+			}
+		}
+	})
+
+	_, token, err := read(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix}, 10)
+	require.NoError(t, err)
+
+	t.Run("tampered token", func(t *testing.T) {
+		for i := range token {
+			tampered := common.CopyBytes(token)
+			tampered[i] ^= 1
+			_, _, err = read(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, ResumeToken: tampered}, n)
+			require.Equal(t, codes.InvalidArgument, status.Code(err), "byte %d", i)
+		}
+		_, _, err = read(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, ResumeToken: token[:len(token)-1]}, n)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("token of other stream", func(t *testing.T) {
+		_, _, err = read(&remote.SeekRequest{BucketName: dbutils.HeaderPrefix, ResumeToken: token}, n)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		_, _, err = read(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, Reverse: true, ResumeToken: token}, n)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		_, _, err = read(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, Prefix: []byte{0}, ResumeToken: token}, n)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		otherServer := dialInMem(t, serveInMem(t, NewKvServer(kv, MaxTxTTL)))
+		stream, err := otherServer.Seek(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, ResumeToken: token}))
+		_, err = stream.Recv()
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestSeekConsistent(t *testing.T) {
+	// scan - reads bucket step by step, writer inserts key 500.5 after first step
+	scan := func(txTTL time.Duration, consistent bool) (keys [][]byte, err error) {
+		kv := ethdb.NewLMDB().InMem().MustOpen()
+		defer kv.Close()
+		writeSequence(t, kv, dbutils.BlockBodyPrefix, 1000)
+		client := dialInMem(t, serveInMem(t, NewKvServer(kv, txTTL)))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		stream, err := client.Seek(ctx)
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, Consistent: consistent}))
+		for {
+			pair, err := stream.Recv()
+			if err != nil {
+				return keys, err
+			}
+			if pair.Key == nil {
+				return keys, nil
+			}
+			keys = append(keys, pair.Key)
+			if len(keys) == 1 {
+				require.NoError(t, kv.Update(context.Background(), func(tx ethdb.Tx) error {
+					return tx.Cursor(dbutils.BlockBodyPrefix).Put([]byte{0, 0, 1, 244, 1}, []byte{1})
+				}))
+			}
+			require.NoError(t, stream.Send(&remote.SeekRequest{}))
+		}
+	}
+
+	// not consistent: server reopens transaction and sees new key in the middle of scan
+	keys, err := scan(time.Nanosecond, false)
+	require.NoError(t, err)
+	require.Equal(t, 1001, len(keys))
+
+	// consistent: new key is not visible, because whole scan is done in one transaction
+	keys, err = scan(MaxTxTTL, true)
+	require.NoError(t, err)
+	require.Equal(t, 1000, len(keys))
+
+	// consistent: if scan takes longer than TTL - error instead of switching to new snapshot
+	_, err = scan(time.Nanosecond, true)
+	require.Equal(t, codes.Aborted, status.Code(err))
+}
+
+func TestCount(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	writeSequence(t, kv, dbutils.BlockBodyPrefix, 600)
+
+	for _, txTTL := range []time.Duration{MaxTxTTL, time.Nanosecond} {
+		client := dialInMem(t, serveInMem(t, NewKvServer(kv, txTTL)))
+		// streamed - amount of pairs which Seek sends for given prefix
+		streamed := func(prefix []byte) (n uint64) {
+			stream, err := client.Seek(context.Background())
+			require.NoError(t, err)
+			require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, Prefix: prefix, SeekKey: prefix, StartStreaming: true}))
+			for {
+				pair, err := stream.Recv()
+				require.NoError(t, err)
+				if pair.Key == nil {
+					return n
+				}
+				n++
+			}
+		}
+
+		for _, prefix := range [][]byte{nil, {0, 0}, {0, 0, 1}, {0, 0, 2}, {0, 0, 3}} {
+			reply, err := client.Count(context.Background(), &remote.CountRequest{BucketName: dbutils.BlockBodyPrefix, Prefix: prefix})
+			require.NoError(t, err)
+			require.Equal(t, streamed(prefix), reply.Count, "prefix %x, ttl %s", prefix, txTTL)
+		}
+	}
+}
+
+func TestSeekProgress(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	writeSequence(t, kv, dbutils.BlockBodyPrefix, 1000)
+	client := dialInMem(t, serveInMem(t, NewKvServer(kv, MaxTxTTL)))
+
+	for _, batchSize := range []uint32{0, 30} {
+		stream, err := client.Seek(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, StartStreaming: true, BatchSize: batchSize, ProgressEvery: 100}))
+
+		var received, progressMessages uint64
+		for {
+			pair, err := stream.Recv()
+			require.NoError(t, err)
+			if pair.Progress != nil {
+				progressMessages++
+				require.Equal(t, uint64(0), received%100, "progress must arrive every 100 keys")
+				require.Equal(t, received, pair.Progress.Keys)
+				require.Equal(t, received*8, pair.Progress.Bytes) // 4 bytes key + 4 bytes value
+				continue
+			}
+			if len(pair.Batch) > 0 {
+				received += uint64(len(pair.Batch))
+				continue
+			}
+			if pair.Key == nil {
+				break
+			}
+			received++
+		}
+		require.Equal(t, uint64(1000), received)
+		require.Equal(t, uint64(10), progressMessages, "batchSize %d", batchSize)
+	}
+}
+
+func TestStageProgress(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	db := ethdb.NewObjectDatabase(kv)
+	require.NoError(t, stages.SaveStageProgress(db, stages.Senders, 12345, []byte{1, 2}))
+	require.NoError(t, stages.SaveStageProgress(db, stages.Execution, 100, nil))
+	client := dialInMem(t, serveInMem(t, NewKvServer(kv, MaxTxTTL)))
+
+	for _, stage := range []stages.SyncStage{stages.Senders, stages.Execution, stages.TxLookup} {
+		expect, _, err := stages.GetStageProgress(db, stage)
+		require.NoError(t, err)
+		reply, err := client.StageProgress(context.Background(), &remote.StageProgressRequest{Stage: string(stage)})
+		require.NoError(t, err)
+		require.Equal(t, expect, reply.Progress, string(stage))
+	}
+	_, err := client.StageProgress(context.Background(), &remote.StageProgressRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// stage progress is as private as any other bucket
+	client = dialInMem(t, serveInMem(t, NewKvServer(kv, MaxTxTTL).WithAllowedBuckets(dbutils.HeaderPrefix)))
+	_, err = client.StageProgress(context.Background(), &remote.StageProgressRequest{Stage: string(stages.Senders)})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestStageThroughput(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	client := dialInMem(t, serveInMem(t, NewKvServer(kv, MaxTxTTL)))
+
+	reply, err := client.StageThroughput(context.Background(), &remote.StageThroughputRequest{Stage: string(stages.TxLookup)})
+	require.NoError(t, err)
+	require.Zero(t, reply.BlocksPerSecond)
+	require.Zero(t, reply.TxsPerSecond)
+	require.Zero(t, reply.Updated, "stage never reported its throughput")
+
+	updated := time.Unix(1600000000, 123*int64(time.Millisecond))
+	stages.SetThroughput(stages.Senders, stages.Throughput{BlocksPerSecond: 250, TxsPerSecond: 30000, Updated: updated})
+	defer stages.SetThroughput(stages.Senders, stages.Throughput{})
+	reply, err = client.StageThroughput(context.Background(), &remote.StageThroughputRequest{Stage: string(stages.Senders)})
+	require.NoError(t, err)
+	require.Equal(t, 250.0, reply.BlocksPerSecond)
+	require.Equal(t, 30000.0, reply.TxsPerSecond)
+	require.Equal(t, int64(1600000000123), reply.Updated)
+
+	_, err = client.StageThroughput(context.Background(), &remote.StageThroughputRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestExportSenders(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	type sender struct {
+		block   uint64
+		hash    common.Hash
+		txIndex uint32
+		address common.Address
+	}
+	var all []sender
+	require.NoError(t, kv.Update(context.Background(), func(tx ethdb.Tx) error {
+		for n := uint64(1); n <= 300; n++ {
+			hash := common.Hash{byte(n), byte(n >> 8), 1}
+			var senders []byte
+			for i := uint64(0); i < n%4; i++ { // blocks without transactions are exported as nothing
+				address := common.Address{byte(n), byte(n >> 8), byte(i)}
+				senders = append(senders, address[:]...)
+				all = append(all, sender{n, hash, uint32(i), address})
+			}
+			if err := tx.Cursor(dbutils.Senders).Put(dbutils.BlockBodyKey(n, hash), senders); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	export := func(t *testing.T, client remote.KVClient, from, to uint64) ([]sender, error) {
+		stream, err := client.ExportSenders(context.Background(), &remote.ExportSendersRequest{FromBlock: from, ToBlock: to})
+		require.NoError(t, err)
+		var exported []sender
+		for {
+			reply, err := stream.Recv()
+			if err == io.EOF {
+				return exported, nil
+			}
+			if err != nil {
+				return exported, err
+			}
+			exported = append(exported, sender{reply.BlockNumber, common.BytesToHash(reply.BlockHash), reply.TxIndex, common.BytesToAddress(reply.Address)})
+		}
+	}
+	between := func(from, to uint64) (expect []sender) {
+		for _, s := range all {
+			if s.block >= from && (to == 0 || s.block <= to) {
+				expect = append(expect, s)
+			}
+		}
+		return expect
+	}
+
+	client := dialInMem(t, serveInMem(t, NewKvServer(kv, MaxTxTTL)))
+	for _, r := range [][2]uint64{{10, 20}, {0, 0}, {299, 0}, {5, 5}, {4, 4}, {301, 0}} {
+		exported, err := export(t, client, r[0], r[1])
+		require.NoError(t, err)
+		require.Equal(t, between(r[0], r[1]), exported, "range %d-%d", r[0], r[1])
+	}
+	_, err := export(t, client, 20, 10)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// read transaction is reopened after each block, export continues after the last exported block
+	client = dialInMem(t, serveInMem(t, NewKvServer(kv, time.Nanosecond)))
+	exported, err := export(t, client, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, all, exported)
+}
+
+func TestFirstLast(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	rnd := rand.New(rand.NewSource(1))
+	require.NoError(t, kv.Update(context.Background(), func(tx ethdb.Tx) error {
+		for i := 0; i < 500; i++ {
+			k, v := make([]byte, 1+rnd.Intn(8)), make([]byte, 1+rnd.Intn(8))
+			rnd.Read(k)
+			rnd.Read(v)
+			if err := tx.Cursor(dbutils.HeaderPrefix).Put(k, v); err != nil {
+				return err
+			}
+			if err := tx.Cursor(dbutils.PlainStateBucket).Put(k[:1], v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	client := dialInMem(t, serveInMem(t, NewKvServer(kv, MaxTxTTL)))
+
+	for _, bucket := range []string{dbutils.HeaderPrefix, dbutils.PlainStateBucket} {
+		var pairs [][2][]byte
+		require.NoError(t, kv.View(context.Background(), func(tx ethdb.Tx) error {
+			c := tx.Cursor(bucket)
+			for k, v, err := c.First(); k != nil; k, v, err = c.Next() {
+				if err != nil {
+					return err
+				}
+				pairs = append(pairs, [2][]byte{common.CopyBytes(k), common.CopyBytes(v)})
+			}
+			return nil
+		}))
+		require.NotEmpty(t, pairs)
+
+		first, err := client.First(context.Background(), &remote.FirstRequest{BucketName: bucket})
+		require.NoError(t, err)
+		require.True(t, first.Found)
+		require.Equal(t, pairs[0], [2][]byte{first.Key, first.Value}, bucket)
+
+		last, err := client.Last(context.Background(), &remote.LastRequest{BucketName: bucket})
+		require.NoError(t, err)
+		require.True(t, last.Found)
+		require.Equal(t, pairs[len(pairs)-1], [2][]byte{last.Key, last.Value}, bucket)
+	}
+
+	first, err := client.First(context.Background(), &remote.FirstRequest{BucketName: dbutils.BlockBodyPrefix})
+	require.NoError(t, err)
+	require.False(t, first.Found, "empty bucket")
+	last, err := client.Last(context.Background(), &remote.LastRequest{BucketName: dbutils.BlockBodyPrefix})
+	require.NoError(t, err)
+	require.False(t, last.Found, "empty bucket")
+
+	_, err = client.Last(context.Background(), &remote.LastRequest{BucketName: "no_such_bucket"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// creditStream - server side of Seek stream, client grants credits and records how many keys it got before each grant
+type creditStream struct {
+	grpc.ServerStream
+	req      *remote.SeekRequest
+	grants   []uint32
+	keys     int
+	keysSeen []int // keys received each time server asked for more credits
+	done     bool  // end of data received
+}
+
+func (s *creditStream) Context() context.Context { return context.Background() }
+func (s *creditStream) Recv() (*remote.SeekRequest, error) {
+	if req := s.req; req != nil {
+		s.req = nil
+		return req, nil
+	}
+	s.keysSeen = append(s.keysSeen, s.keys)
+	if len(s.grants) == 0 {
+		return nil, io.EOF
+	}
+	grant := s.grants[0]
+	s.grants = s.grants[1:]
+	return &remote.SeekRequest{Credits: grant}, nil
+}
+func (s *creditStream) Send(pair *remote.Pair) error {
+	if pair.Key != nil {
+		s.keys++
+	} else if len(pair.Batch) == 0 {
+		s.done = true
+	}
+	s.keys += len(pair.Batch)
+	return nil
+}
+
+func TestSeekCredits(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	writeSequence(t, kv, dbutils.BlockBodyPrefix, 100)
+	srv := NewKvServer(kv, MaxTxTTL)
+
+	for _, batchSize := range []uint32{0, 4} {
+		stream := &creditStream{
+			req:    &remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, Credits: 5, BatchSize: batchSize},
+			grants: []uint32{10, 3, 7, 1000},
+		}
+		require.NoError(t, srv.Seek(stream))
+		require.Equal(t, []int{5, 15, 18, 25}, stream.keysSeen, "batchSize=%d", batchSize)
+		require.Equal(t, 100, stream.keys)
+		require.True(t, stream.done, "end of data is sent without credit")
+	}
+
+	// client doesn't grant more - stream ends without sending keys above the window
+	stream := &creditStream{req: &remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, Credits: 7}}
+	require.NoError(t, srv.Seek(stream))
+	require.Equal(t, []int{7}, stream.keysSeen)
+	require.Equal(t, 7, stream.keys)
+	require.False(t, stream.done)
+}
+
+func TestStartGrpcUnixSocket(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	writeSequence(t, kv, dbutils.PlainStateBucket, 10)
+
+	dir, err := ioutil.TempDir("", "tg-socket") // t.TempDir may exceed the socket path length limit
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "private_api.sock")
+
+	// socket file left by a crashed node
+	stale, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	addr := "unix://" + socket
+	grpcServer, err := StartGrpc(kv, nil, DefaultGrpcConfig(addr))
+	require.NoError(t, err)
+
+	_, err = StartGrpc(kv, nil, DefaultGrpcConfig(addr))
+	require.Error(t, err, "socket of running server must not be taken over")
+
+	remoteKV, _, err := ethdb.NewRemote().Path(addr).Open("", "", "")
+	require.NoError(t, err)
+	var keys int
+	require.NoError(t, remoteKV.View(context.Background(), func(tx ethdb.Tx) error {
+		c := tx.Cursor(dbutils.PlainStateBucket)
+		for k, _, err := c.Seek([]byte{0, 0, 0, 3}); k != nil; k, _, err = c.Next() {
+			if err != nil {
+				return err
+			}
+			keys++
+		}
+		return nil
+	}))
+	require.Equal(t, 7, keys)
+	remoteKV.Close()
+
+	grpcServer.GracefulStop()
+	_, err = os.Stat(socket)
+	require.True(t, os.IsNotExist(err), "socket file must be removed on stop")
+}
+
+func TestSeekEndKey(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	writeSequence(t, kv, dbutils.BlockBodyPrefix, 600)
+	client := dialInMem(t, serveInMem(t, NewKvServer(kv, MaxTxTTL)))
+
+	scan := func(req *remote.SeekRequest, streaming bool) (keys []uint32) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		stream, err := client.Seek(ctx)
+		require.NoError(t, err)
+		req.BucketName, req.StartStreaming = dbutils.BlockBodyPrefix, streaming
+		require.NoError(t, stream.Send(req))
+		for {
+			pair, err := stream.Recv()
+			require.NoError(t, err)
+			for _, p := range pair.Batch {
+				keys = append(keys, binary.BigEndian.Uint32(p.Key))
+			}
+			if pair.Key == nil && len(pair.Batch) == 0 {
+				return keys
+			}
+			if pair.Key != nil {
+				keys = append(keys, binary.BigEndian.Uint32(pair.Key))
+			}
+			if !streaming {
+				require.NoError(t, stream.Send(&remote.SeekRequest{}))
+			}
+		}
+	}
+	sequence := func(from, to uint32) (keys []uint32) {
+		for i := from; i < to; i++ {
+			keys = append(keys, i)
+		}
+		return keys
+	}
+	cases := []struct {
+		name     string
+		req      *remote.SeekRequest
+		expected []uint32
+	}{
+		{"half-open range", &remote.SeekRequest{SeekKey: []byte{0, 0, 0, 10}, EndKey: []byte{0, 0, 0, 20}}, sequence(10, 20)},
+		{"end key is missing", &remote.SeekRequest{SeekKey: []byte{0, 0, 0, 10}, EndKey: []byte{0, 0, 0, 20, 1}}, sequence(10, 21)},
+		{"short end key excludes keys it prefixes", &remote.SeekRequest{SeekKey: []byte{0, 0, 0, 250}, EndKey: []byte{0, 0, 1}}, sequence(250, 256)},
+		{"end key after prefix", &remote.SeekRequest{Prefix: []byte{0, 0, 1}, SeekKey: []byte{0, 0, 1}, EndKey: []byte{0, 0, 3}}, sequence(256, 512)},
+		{"end key inside prefix", &remote.SeekRequest{Prefix: []byte{0, 0, 1}, SeekKey: []byte{0, 0, 1}, EndKey: []byte{0, 0, 1, 5}}, sequence(256, 261)},
+		{"end key after last", &remote.SeekRequest{SeekKey: []byte{0, 0, 2, 80}, EndKey: []byte{1}}, sequence(592, 600)},
+		{"end key before seek key", &remote.SeekRequest{SeekKey: []byte{0, 0, 0, 10}, EndKey: []byte{0, 0, 0, 5}}, nil},
+		{"batched", &remote.SeekRequest{SeekKey: []byte{0, 0, 0, 10}, EndKey: []byte{0, 0, 0, 30}, BatchSize: 7}, sequence(10, 30)},
+	}
+	for _, tc := range cases {
+		for _, streaming := range []bool{false, true} {
+			require.Equal(t, tc.expected, scan(tc.req, streaming), "%s, streaming=%t", tc.name, streaming)
+		}
+	}
+
+	stream, err := client.Seek(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, Reverse: true, EndKey: []byte{1}}))
+	_, err = stream.Recv()
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// BenchmarkSeekTxReopen - cost of a scan which outlives read transaction TTL: after each reopen cursor is
+// positioned again from the root of the tree, with the shortest TTL it happens almost for every key
+func BenchmarkSeekTxReopen(b *testing.B) {
+	db := ethdb.NewLMDB().InMem().MustOpen()
+	defer db.Close()
+	const keys = 100_000
+	require.NoError(b, db.Update(context.Background(), func(tx ethdb.Tx) error {
+		c := tx.Cursor(dbutils.BlockBodyPrefix)
+		for i := uint32(0); i < keys; i++ {
+			k := make([]byte, 40) // like block number and hash
+			binary.BigEndian.PutUint32(k, i)
+			if err := c.Append(k, k); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	for _, ttl := range []time.Duration{MaxTxTTL, time.Millisecond, time.Nanosecond} {
+		b.Run(fmt.Sprintf("ttl=%s", ttl), func(b *testing.B) {
+			kv := &txCountingKV{KV: db}
+			srv := NewKvServer(kv, ttl)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				stream := &creditStream{req: &remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, StartStreaming: true}}
+				if err := srv.Seek(stream); err != nil {
+					b.Fatal(err)
+				}
+				if stream.keys != keys {
+					b.Fatalf("got %d keys", stream.keys)
+				}
+			}
+			b.ReportMetric(float64(atomic.LoadInt32(&kv.begun)-int32(b.N))/float64(b.N), "reopens/op")
+		})
+	}
+}
+
+func TestStartGrpcTracing(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	writeSequence(t, kv, dbutils.BlockBodyPrefix, 10)
+
+	tracer := mocktracer.New()
+	addr := freeAddr(t)
+	cfg := DefaultGrpcConfig(addr)
+	cfg.Tracer = tracer
+	grpcServer, err := StartGrpc(kv, nil, cfg)
+	require.NoError(t, err)
+	defer grpcServer.Stop()
+	clientConn, err := grpc.Dial(addr, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer clientConn.Close()
+
+	// client passes its trace in metadata
+	parent := tracer.StartSpan("client")
+	carrier := opentracing.HTTPHeadersCarrier{}
+	require.NoError(t, tracer.Inject(parent.Context(), opentracing.HTTPHeaders, carrier))
+	md := metadata.MD{}
+	for k, v := range carrier {
+		md.Set(k, v...)
+	}
+	ctx, cancel := context.WithCancel(metadata.NewOutgoingContext(context.Background(), md))
+	defer cancel()
+	stream, err := remote.NewKVClient(clientConn).Seek(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&remote.SeekRequest{BucketName: dbutils.BlockBodyPrefix, StartStreaming: true}))
+	for {
+		pair, err := stream.Recv()
+		require.NoError(t, err)
+		if pair.Key == nil {
+			break
+		}
+	}
+	parent.Finish()
+
+	var span *mocktracer.MockSpan
+	require.Eventually(t, func() bool {
+		for _, s := range tracer.FinishedSpans() {
+			if s.OperationName == "/remote.KV/Seek" {
+				span = s
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond, "server didn't finish span of Seek")
+	require.Equal(t, parent.(*mocktracer.MockSpan).SpanContext.TraceID, span.SpanContext.TraceID)
+	require.Equal(t, parent.(*mocktracer.MockSpan).SpanContext.SpanID, span.ParentID)
+	require.Equal(t, dbutils.BlockBodyPrefix, span.Tag("bucket"))
+	require.Equal(t, uint64(10), span.Tag("keys"))
+
+	_, err = remote.NewKVClient(clientConn).MultiSeekExact(context.Background(), &remote.MultiSeekExactRequest{
+		BucketNames: []string{dbutils.BlockBodyPrefix, dbutils.HeaderPrefix},
+		Key:         []byte{0, 0, 0, 1},
+	})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		for _, s := range tracer.FinishedSpans() {
+			if s.OperationName == "/remote.KV/MultiSeekExact" {
+				span = s
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond, "server didn't finish span of MultiSeekExact")
+	require.Equal(t, dbutils.BlockBodyPrefix+","+dbutils.HeaderPrefix, span.Tag("bucket"))
+}
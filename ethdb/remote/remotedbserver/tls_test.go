@@ -0,0 +1,107 @@
+package remotedbserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/turbo-geth/common/dbutils"
+	"github.com/ledgerwatch/turbo-geth/ethdb"
+)
+
+// writeSelfSignedCert - creates cert which can be used as server cert, client cert and CA at the same time
+func writeSelfSignedCert(t *testing.T) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "turbo-geth test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func TestStartGrpcTLS(t *testing.T) {
+	kv := ethdb.NewLMDB().InMem().MustOpen()
+	defer kv.Close()
+	writeSequence(t, kv, dbutils.BlockBodyPrefix, 10)
+	certFile, keyFile := writeSelfSignedCert(t)
+
+	// read - does Seek over the remote connection
+	read := func(remoteKV ethdb.KV) error {
+		return remoteKV.View(context.Background(), func(tx ethdb.Tx) error {
+			k, _, err := tx.Cursor(dbutils.BlockBodyPrefix).Seek([]byte{0, 0, 0, 5})
+			if err != nil {
+				return err
+			}
+			require.Equal(t, []byte{0, 0, 0, 5}, k)
+			return nil
+		})
+	}
+	start := func(caFile string) string {
+		creds, err := TLS(certFile, keyFile, caFile)
+		require.NoError(t, err)
+		addr := freeAddr(t)
+		cfg := DefaultGrpcConfig(addr)
+		cfg.Creds = &creds
+		grpcServer, err := StartGrpc(kv, nil, cfg)
+		require.NoError(t, err)
+		t.Cleanup(grpcServer.Stop)
+		return addr
+	}
+
+	t.Run("tls", func(t *testing.T) {
+		addr := start("")
+
+		remoteKV, _, err := ethdb.NewRemote().Path(addr).Open(certFile, "", "")
+		require.NoError(t, err)
+		defer remoteKV.Close()
+		require.NoError(t, read(remoteKV))
+
+		insecureKV, _, err := ethdb.NewRemote().Path(addr).Open("", "", "")
+		require.NoError(t, err)
+		defer insecureKV.Close()
+		require.Error(t, read(insecureKV))
+	})
+
+	t.Run("mutual tls", func(t *testing.T) {
+		addr := start(certFile)
+
+		remoteKV, _, err := ethdb.NewRemote().Path(addr).Open(certFile, keyFile, certFile)
+		require.NoError(t, err)
+		defer remoteKV.Close()
+		require.NoError(t, read(remoteKV))
+
+		noClientCertKV, _, err := ethdb.NewRemote().Path(addr).Open(certFile, "", "")
+		require.NoError(t, err)
+		defer noClientCertKV.Close()
+		require.Error(t, read(noClientCertKV))
+	})
+}
@@ -0,0 +1,94 @@
+package remotedbserver
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var errInvalidResumeToken = errors.New("invalid resume token")
+
+// resumeToken - position of Seek stream: the stream continues after lastKey
+type resumeToken struct {
+	bucket  string
+	prefix  []byte
+	reverse bool
+	lastKey []byte
+}
+
+// tokenSigner - signs resume tokens by HMAC, then client can't resume stream from a position
+// which server didn't send to it (e.g. in other bucket)
+type tokenSigner struct {
+	key []byte
+}
+
+// newTokenSigner - tokens signed with random key are valid only until server restart
+func newTokenSigner() *tokenSigner {
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Errorf("generate resume token key: %w", err))
+	}
+	return &tokenSigner{key: key}
+}
+
+// token layout: uvarint len(bucket), bucket, uvarint len(prefix), prefix, reverse byte, lastKey, hmac-sha256
+func (s *tokenSigner) sign(t resumeToken) []byte {
+	var lenBuf [binary.MaxVarintLen64]byte
+	buf := make([]byte, 0, 2*binary.MaxVarintLen64+len(t.bucket)+len(t.prefix)+1+len(t.lastKey)+sha256.Size)
+	buf = append(buf, lenBuf[:binary.PutUvarint(lenBuf[:], uint64(len(t.bucket)))]...)
+	buf = append(buf, t.bucket...)
+	buf = append(buf, lenBuf[:binary.PutUvarint(lenBuf[:], uint64(len(t.prefix)))]...)
+	buf = append(buf, t.prefix...)
+	if t.reverse {
+		buf = append(buf, 1)
+	} else {
+		buf = append(buf, 0)
+	}
+	buf = append(buf, t.lastKey...)
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(buf)
+	return mac.Sum(buf)
+}
+
+func (s *tokenSigner) verify(token []byte) (resumeToken, error) {
+	if len(token) < sha256.Size {
+		return resumeToken{}, fmt.Errorf("%w: too short", errInvalidResumeToken)
+	}
+	data, sum := token[:len(token)-sha256.Size], token[len(token)-sha256.Size:]
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(data)
+	if !hmac.Equal(sum, mac.Sum(nil)) {
+		return resumeToken{}, fmt.Errorf("%w: signature mismatch", errInvalidResumeToken)
+	}
+
+	r := bytes.NewReader(data)
+	readBytes := func() ([]byte, error) {
+		l, err := binary.ReadUvarint(r)
+		if err != nil || l > uint64(r.Len()) {
+			return nil, fmt.Errorf("%w: malformed", errInvalidResumeToken)
+		}
+		b := make([]byte, l)
+		_, _ = r.Read(b)
+		return b, nil
+	}
+	var t resumeToken
+	bucket, err := readBytes()
+	if err != nil {
+		return resumeToken{}, err
+	}
+	t.bucket = string(bucket)
+	if t.prefix, err = readBytes(); err != nil {
+		return resumeToken{}, err
+	}
+	reverse, err := r.ReadByte()
+	if err != nil {
+		return resumeToken{}, fmt.Errorf("%w: malformed", errInvalidResumeToken)
+	}
+	t.reverse = reverse == 1
+	t.lastKey = data[len(data)-r.Len():]
+	return t, nil
+}
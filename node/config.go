@@ -25,6 +25,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ledgerwatch/turbo-geth/accounts"
 	"github.com/ledgerwatch/turbo-geth/accounts/external"
@@ -186,6 +187,28 @@ type Config struct {
 	// empty string means not to start the listener
 	PrivateApiAddr string
 
+	// Bearer token which clients of private api must send, empty string means no authentication
+	PrivateApiToken string
+
+	// Max concurrent streams per client connection of private api, 0 means default
+	PrivateApiMaxStreams uint32
+
+	// Requests per second which one client host can send to private api, 0 means unlimited
+	PrivateApiRateLimit float64
+	PrivateApiRateBurst int
+
+	// Buckets which clients of private api can access, empty means all buckets
+	PrivateApiBuckets []string
+
+	// Max read transactions open by private api at once, 0 means unlimited,
+	// and how long a request waits for one, 0 means default
+	PrivateApiMaxReadTxs     int
+	PrivateApiMaxReadTxsWait time.Duration
+
+	// How long private api waits for a client to read streamed data before releasing its transaction,
+	// 0 means default, negative means forever
+	PrivateApiStaleClientTimeout time.Duration
+
 	staticNodesWarning     bool
 	trustedNodesWarning    bool
 	oldGethResourceWarning bool
@@ -25,6 +25,11 @@ var DefaultFlags = []cli.Flag{
 	utils.TxLookupLimitFlag,
 	utils.StorageModeFlag,
 	utils.HddFlag,
+	utils.SendersWorkersFlag,
+	utils.SendersMaxHeapFlag,
+	utils.SendersLockOSThreadFlag,
+	utils.SendersMaxTempFilesFlag,
+	utils.SendersSkipInvalidChainIDFlag,
 	utils.DatabaseFlag,
 	utils.LMDBMapSizeFlag,
 	utils.TLSFlag,
@@ -32,6 +37,14 @@ var DefaultFlags = []cli.Flag{
 	utils.TLSKeyFlag,
 	utils.TLSCACertFlag,
 	utils.PrivateApiAddr,
+	utils.PrivateApiToken,
+	utils.PrivateApiMaxStreams,
+	utils.PrivateApiRateLimit,
+	utils.PrivateApiRateBurst,
+	utils.PrivateApiBuckets,
+	utils.PrivateApiMaxReadTxs,
+	utils.PrivateApiMaxReadTxsWait,
+	utils.PrivateApiStaleClientTimeout,
 	utils.ListenPortFlag,
 	utils.NATFlag,
 	utils.NoDiscoverFlag,